@@ -0,0 +1,176 @@
+// backup exports the settings and history to a json bundle or csv, and imports a bundle into a data folder.
+// Run it while the song request app is closed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/datadir"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/helpers"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/tokencrypt"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  backup [-data-dir DIR] export [-include-tokens] [-o FILE]
+  backup [-data-dir DIR] history-csv [-o FILE]
+  backup [-data-dir DIR] redact -o FILE
+  backup [-data-dir DIR] import [-mode skip|overwrite] FILE`)
+	os.Exit(2)
+}
+
+func main() {
+	dataDirFlag := flag.String("data-dir", "", "data folder to use, defaults to $"+datadir.EnvVar+" or the OS app data folder")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	dataDir, err := datadir.Resolve(*dataDirFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	dbPath := databaseconn.Path(dataDir)
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		includeTokens := fs.Bool("include-tokens", false, "also export the twitch tokens, keep the file private")
+		out := fs.String("o", "", "output file, stdout when empty")
+		fs.Parse(flag.Args()[1:])
+
+		st := openExisting(dbPath)
+		defer st.Close()
+		bundle, err := st.Export(ctx, store.ExportOptions{IncludeTokens: *includeTokens})
+		if err != nil {
+			log.Fatalln("Export failed", err)
+		}
+		writeOutput(*out, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(bundle)
+		})
+	case "history-csv":
+		fs := flag.NewFlagSet("history-csv", flag.ExitOnError)
+		out := fs.String("o", "", "output file, stdout when empty")
+		fs.Parse(flag.Args()[1:])
+
+		st := openExisting(dbPath)
+		defer st.Close()
+		writeOutput(*out, func(w io.Writer) error {
+			return st.WriteHistoryCSV(ctx, w)
+		})
+	case "redact":
+		fs := flag.NewFlagSet("redact", flag.ExitOnError)
+		out := fs.String("o", "", "where to write the database copy without tokens")
+		fs.Parse(flag.Args()[1:])
+		if *out == "" {
+			usage()
+		}
+
+		st := openExisting(dbPath)
+		defer st.Close()
+		if err := st.WriteRedactedCopy(ctx, *out); err != nil {
+			log.Fatalln("Redacted copy failed", err)
+		}
+		log.Println("Wrote", *out, "without tokens, it can be attached to a bug report")
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		modeFlag := fs.String("mode", string(store.ImportSkip), "what to do with settings, songs, credits and blocklist entries that already exist: skip or overwrite")
+		fs.Parse(flag.Args()[1:])
+		if fs.NArg() != 1 {
+			usage()
+		}
+		mode, err := store.ParseImportMode(*modeFlag)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		b, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		bundle := store.Bundle{}
+		if err := json.Unmarshal(b, &bundle); err != nil {
+			log.Fatalln("Not an export bundle", err)
+		}
+
+		// importing into a fresh data folder is how a setup moves to another PC
+		if err := datadir.Ensure(dataDir); err != nil {
+			log.Fatalln(err)
+		}
+		if err := databaseconn.Create(dbPath); err != nil {
+			log.Fatalln(err)
+		}
+		if err := helpers.PreflightTest(dbPath); err != nil {
+			log.Fatalln("Migration failed", err)
+		}
+		st := openExisting(dbPath)
+		defer st.Close()
+		result, err := st.Import(ctx, bundle, mode)
+		if err != nil {
+			log.Fatalln("Import failed, nothing was changed:", err)
+		}
+		for _, v := range []struct {
+			name   string
+			counts store.ImportCounts
+		}{
+			{"settings", result.Settings},
+			{"songs", result.Songs},
+			{"requests", result.Requests},
+			{"plays", result.Plays},
+			{"credits", result.Credits},
+			{"blocklist", result.Blocklist},
+		} {
+			fmt.Printf("%-10s added %d, updated %d, skipped %d\n", v.name, v.counts.Added, v.counts.Updated, v.counts.Skipped)
+		}
+	default:
+		usage()
+	}
+}
+
+func openExisting(dbPath string) *store.Store {
+	if _, err := os.Stat(dbPath); err != nil {
+		log.Fatalln("No database at", dbPath)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// same key as the app so exported tokens come out readable and imported ones get encrypted
+	err = st.EnableTokenEncryption(context.Background(), filepath.Dir(dbPath))
+	if err != nil {
+		log.Fatalln("Cannot decrypt the Twitch tokens, start the app once or set "+tokencrypt.PassphraseEnvVar, err)
+	}
+	return st
+}
+
+func writeOutput(path string, write func(w io.Writer) error) {
+	if path == "" {
+		if err := write(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		log.Fatalln(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Wrote", path)
+}
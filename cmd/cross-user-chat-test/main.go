@@ -1,30 +1,32 @@
 package main
 
-//lint:file-ignore ST1001 Dot imports by jet
 import (
+	"context"
 	"log"
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/datadir"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
 	"github.com/nicklaw5/helix/v2"
-
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
-	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
-	. "github.com/go-jet/jet/v2/sqlite"
 )
 
 func main() {
-	db, err := databaseconn.NewDBConnection()
+	dataDir, err := datadir.Resolve("")
+	if err != nil {
+		panic(err)
+	}
+	st, err := store.Open(databaseconn.Path(dataDir))
+	if err != nil {
+		panic(err)
+	}
+	defer st.Close()
+	err = st.EnableTokenEncryption(context.Background(), dataDir)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
-
-	stmt := SELECT(Settings.Key, Settings.Value).FROM(Settings)
-
-	ss := []model.Settings{}
 
-	err = stmt.Query(db, &ss)
+	ss, err := st.Settings.All(context.Background())
 	if err != nil {
 		panic(err)
 	}
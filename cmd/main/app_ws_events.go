@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/labstack/echo/v4"
+)
+
+// Bump when a field of these events changes meaning or goes away, adding fields is fine
+const appWsEventsVersion = 1
+
+const (
+	appWsEventNowPlaying      = "NOW_PLAYING"
+	appWsEventPosition        = "POSITION"
+	appWsEventQueueUpdated    = "QUEUE_UPDATED"
+	appWsEventRequestAdded    = "REQUEST_ADDED"
+	appWsEventRequestRejected = "REQUEST_REJECTED"
+)
+
+// Why a request did not make it into the queue
+const (
+	requestRejectedClosed        = "closed"
+	requestRejectedBanned        = "banned"
+	requestRejectedBlocked       = "blocked"
+	requestRejectedNotFound      = "not_found"
+	requestRejectedAlreadyQueued = "already_queued"
+	requestRejectedNoCredits     = "no_credits"
+	requestRejectedNotApproved   = "not_approved"
+	requestRejectedError         = "error"
+)
+
+func appWsEvent(eventType string, fields echo.Map) string {
+	fields["v"] = appWsEventsVersion
+	fields["type"] = eventType
+	b, _ := json.Marshal(fields)
+	return string(b)
+}
+
+// nowPlayingEvent needs songQueueMutex held
+func nowPlayingEvent() string {
+	return appWsEvent(appWsEventNowPlaying, echo.Map{
+		"song": echo.Map{
+			"video_id":  playerInfo.Song.VideoId,
+			"title":     playerInfo.Song.AlternativeTitle,
+			"artist":    playerInfo.Song.Artist,
+			"image_url": playerInfo.Song.ImageSrc,
+			"duration":  playerInfo.Song.SongDuration,
+			"url":       playerInfo.Song.GetUrl(),
+		},
+		"requested_by": playerInfo.RequestedBy,
+		"is_playing":   playerInfo.IsPlaying,
+		"position":     playerInfo.Position,
+	})
+}
+
+// positionEvent needs songQueueMutex held
+func positionEvent() string {
+	return appWsEvent(appWsEventPosition, echo.Map{
+		"video_id":   playerInfo.Song.VideoId,
+		"is_playing": playerInfo.IsPlaying,
+		"position":   playerInfo.Position,
+		"duration":   playerInfo.Song.SongDuration,
+	})
+}
+
+// broadcastRequestAdded sends clients without a session only what the request toast overlay shows
+func (a *App) broadcastRequestAdded(item songQueueItem) {
+	request := echo.Map{
+		"id":           item.song.VideoID,
+		"title":        item.song.Title,
+		"artist":       item.song.Artist,
+		"image_url":    item.song.ImageUrl,
+		"url":          "https://youtu.be/" + item.song.VideoID,
+		"requested_by": item.requestedBy,
+	}
+	public := appWsEvent(appWsEventRequestAdded, echo.Map{
+		"request": request,
+	})
+	request["requested_by_id"] = item.requestedByID
+	request["requested_at"] = item.requestedAt
+	request["source"] = item.source
+	a.broadcastSplit(appWsEvent(appWsEventRequestAdded, echo.Map{
+		"request": request,
+	}), public)
+}
+
+func (a *App) broadcastRequestRejected(event twitch.EventChannelChatMessage, song *songrequests.SongResult, reason string) {
+	fields := echo.Map{
+		"reason":          reason,
+		"requested_by":    event.ChatterUserLogin,
+		"requested_by_id": event.ChatterUserId,
+		"query":           strings.TrimPrefix(event.Message.Text, "!sr "),
+	}
+	if song != nil {
+		fields["video_id"] = song.VideoID
+		fields["title"] = song.Title
+		fields["artist"] = song.Artist
+	}
+	a.broadcastAuthenticated(appWsEvent(appWsEventRequestRejected, fields))
+}
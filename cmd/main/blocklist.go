@@ -0,0 +1,294 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/labstack/echo/v4"
+	"github.com/nicklaw5/helix/v2"
+)
+
+type songBlocker struct {
+	entry   model.Blocklist
+	blocker songrequests.SongBlocker
+}
+
+var songBlockersMutex = sync.Mutex{}
+
+// songBlockers is nil until the song entries are loaded, changing the blocklist sets it back to nil
+var songBlockers []songBlocker
+
+func resetSongBlockers() {
+	songBlockersMutex.Lock()
+	songBlockers = nil
+	songBlockersMutex.Unlock()
+}
+
+// songBlocked returns the first blocklist entry that rules out the song
+func (a *App) songBlocked(song *songrequests.SongResult) (model.Blocklist, bool) {
+	songBlockersMutex.Lock()
+	defer songBlockersMutex.Unlock()
+	if songBlockers == nil {
+		entries, err := a.store.Blocklist.List(a.ctx, songrequests.SongBlocklistKinds...)
+		if err != nil {
+			log.Println("Failed to check the song blocklist", err)
+			return model.Blocklist{}, false
+		}
+		songBlockers = []songBlocker{}
+		for _, v := range entries {
+			blocker, err := songrequests.NewSongBlocker(v.Kind, v.Value)
+			if err != nil {
+				log.Println("Skipping blocklist entry", v.Kind, v.Value, err)
+				continue
+			}
+			songBlockers = append(songBlockers, songBlocker{entry: v, blocker: blocker})
+		}
+	}
+	for _, v := range songBlockers {
+		if v.blocker.Blocks(song) {
+			return v.entry, true
+		}
+	}
+	return model.Blocklist{}, false
+}
+
+// addBlocklistEntry normalizes the value, false means it was listed already
+func (a *App) addBlocklistEntry(kind songrequests.BlocklistKind, value string, label string) (model.Blocklist, bool, error) {
+	if kind == songrequests.BlocklistKindUser {
+		// the control panel may send a login, bans are kept by ID since logins can be renamed
+		if _, err := songrequests.NormalizeBlocklistValue(kind, value); err != nil {
+			userID, login, err := a.lookupTwitchUser(value)
+			if err != nil {
+				return model.Blocklist{}, false, err
+			}
+			value, label = userID, login
+		}
+	}
+	value, err := songrequests.NormalizeBlocklistValue(kind, value)
+	if err != nil {
+		return model.Blocklist{}, false, err
+	}
+	if label == "" && kind == songrequests.BlocklistKindVideo {
+		if song, err := a.store.Requests.Song(a.ctx, value); err == nil {
+			label = song.SongTitle + " - " + song.ArtistName
+		}
+	}
+	added, err := a.store.Blocklist.Add(a.ctx, kind, value, label)
+	if err != nil {
+		return model.Blocklist{}, false, err
+	}
+	entry := model.Blocklist{Kind: kind, Value: value, Label: label}
+	if added {
+		resetSongBlockers()
+		entry.CreatedAt = store.FormatTime(time.Now())
+		log.Println("Blocklisted", kind, value, label)
+	}
+	return entry, added, nil
+}
+
+// lookupTwitchUser turns a login from chat, with or without @, into the user ID bans are kept by
+func (a *App) lookupTwitchUser(login string) (string, string, error) {
+	login = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(login), "@"))
+	if login == "" {
+		return "", "", errors.New("no user given")
+	}
+	resp, err := a.helix.GetUsers(&helix.UsersParams{
+		Logins: []string{login},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(resp.Data.Users) == 0 {
+		return "", "", errors.New("no Twitch user " + login)
+	}
+	return resp.Data.Users[0].ID, resp.Data.Users[0].Login, nil
+}
+
+// currentSongBlockTarget is the song !srblock and !srunblock act on without arguments
+func currentSongBlockTarget() (string, string) {
+	songQueueMutex.RLock()
+	defer songQueueMutex.RUnlock()
+	if playerInfo.Song.VideoId == "" {
+		return "", ""
+	}
+	return playerInfo.Song.VideoId, playerInfo.Song.AlternativeTitle + " - " + playerInfo.Song.Artist
+}
+
+func isBlocklistCommand(text string) bool {
+	command, _, _ := strings.Cut(strings.TrimSpace(text), " ")
+	command = strings.ToLower(command)
+	return command == "!srban" || command == "!srblock" || command == "!srunblock"
+}
+
+// blocklistCommandReply runs !srban @user, !srblock [song] and !srunblock [@user or song],
+// the song is the one playing when none is given
+func (a *App) blocklistCommandReply(event twitch.EventChannelChatMessage) string {
+	command, arg, _ := strings.Cut(strings.TrimSpace(event.Message.Text), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "!srban":
+		if arg == "" {
+			return "Usage: !srban @user"
+		}
+		userID, login, err := a.lookupTwitchUser(arg)
+		if err != nil {
+			return "Could not find " + arg + "."
+		}
+		if err := a.banRequester(userID, login); err != nil {
+			log.Println("Failed to ban requester", err)
+			return "Failed to ban " + login + "."
+		}
+		return login + " can no longer request songs."
+	case "!srblock":
+		videoID, label := arg, ""
+		if arg == "" {
+			videoID, label = currentSongBlockTarget()
+			if videoID == "" {
+				return "Nothing is playing, use !srblock <link>"
+			}
+		}
+		entry, added, err := a.addBlocklistEntry(songrequests.BlocklistKindVideo, videoID, label)
+		if err != nil {
+			return "Usage: !srblock [YouTube link or videoId]"
+		}
+		name := entry.Label
+		if name == "" {
+			name = "https://youtu.be/" + entry.Value
+		}
+		if !added {
+			return name + " is already blocked."
+		}
+		return "Blocked " + name + " from requests."
+	case "!srunblock":
+		kind, value := songrequests.BlocklistKindVideo, ""
+		if arg == "" {
+			value, _ = currentSongBlockTarget()
+			if value == "" {
+				return "Nothing is playing, use !srunblock <link or @user>"
+			}
+		} else if videoID, err := songrequests.NormalizeBlocklistValue(songrequests.BlocklistKindVideo, arg); err == nil && !strings.HasPrefix(arg, "@") {
+			value = videoID
+		} else {
+			userID, _, err := a.lookupTwitchUser(arg)
+			if err != nil {
+				return "Could not find " + arg + "."
+			}
+			kind, value = songrequests.BlocklistKindUser, userID
+		}
+		removed, err := a.store.Blocklist.Remove(a.ctx, kind, value)
+		if err != nil {
+			log.Println("Failed to remove blocklist entry", err)
+			return "Failed to unblock " + arg + "."
+		}
+		if !removed {
+			return "That is not blocked."
+		}
+		resetSongBlockers()
+		log.Println("Removed from blocklist", kind, value)
+		if kind == songrequests.BlocklistKindUser {
+			return strings.TrimPrefix(arg, "@") + " can request songs again."
+		}
+		return "Unblocked, the song can be requested again."
+	}
+	return ""
+}
+
+func (a *App) replyBlocklistCommand(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage) {
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              a.blocklistCommandReply(event),
+		ReplyParentMessageID: event.MessageId,
+	})
+}
+
+type blocklistItem struct {
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+	Label     string `json:"label"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// getBlocklist lists every entry, or one kind with ?kind=
+func (a *App) getBlocklist(c echo.Context) error {
+	kinds := []songrequests.BlocklistKind{}
+	if kind := c.QueryParam("kind"); kind != "" {
+		if !songrequests.IsBlocklistKind(kind) {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "kind must be one of " + strings.Join(songrequests.BlocklistKinds, ", "),
+			})
+		}
+		kinds = append(kinds, kind)
+	}
+	entries, err := a.store.Blocklist.List(c.Request().Context(), kinds...)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read the blocklist",
+		})
+	}
+	items := []blocklistItem{}
+	for _, v := range entries {
+		items = append(items, blocklistItem(v))
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"items": items,
+	})
+}
+
+func (a *App) postBlocklist(c echo.Context) error {
+	body := blocklistItem{}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "body must be a blocklist entry",
+		})
+	}
+	entry, added, err := a.addBlocklistEntry(body.Kind, body.Value, strings.TrimSpace(body.Label))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	return c.JSON(status, echo.Map{
+		"added": added,
+		"item":  blocklistItem(entry),
+	})
+}
+
+// deleteBlocklist takes ?kind=&value=, regex values do not fit in a path
+func (a *App) deleteBlocklist(c echo.Context) error {
+	kind := c.QueryParam("kind")
+	value, err := songrequests.NormalizeBlocklistValue(kind, c.QueryParam("value"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	removed, err := a.store.Blocklist.Remove(c.Request().Context(), kind, value)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot update the blocklist",
+		})
+	}
+	if !removed {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": "entry not found in the blocklist",
+		})
+	}
+	resetSongBlockers()
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/utils"
+	"github.com/nicklaw5/helix/v2"
+)
+
+var defaultPermissionRules = map[string]songrequests.PermissionRule{
+	data.DB_KEY_PERMISSION_SR:        {songrequests.ChatterRoleSubscriber, songrequests.ChatterRoleFounder, songrequests.ChatterRoleModerator},
+	data.DB_KEY_PERMISSION_SR_REWARD: {songrequests.ChatterRoleEveryone},
+	data.DB_KEY_PERMISSION_SKIP:      {songrequests.ChatterRoleModerator},
+	data.DB_KEY_PERMISSION_SONG:      {songrequests.ChatterRoleEveryone},
+	data.DB_KEY_PERMISSION_QUEUE:     {songrequests.ChatterRoleEveryone},
+	data.DB_KEY_PERMISSION_ANNOUNCE:  {songrequests.ChatterRoleModerator},
+	data.DB_KEY_PERMISSION_STATS:     {songrequests.ChatterRoleEveryone},
+	data.DB_KEY_PERMISSION_BLOCKLIST: {songrequests.ChatterRoleModerator},
+	// checked on top of the request permission for partner channel chatters in restrict mode
+	data.DB_KEY_PERMISSION_SHARED_CHAT: {
+		songrequests.ChatterRoleFollower,
+		songrequests.ChatterRoleSubscriber,
+		songrequests.ChatterRoleFounder,
+		songrequests.ChatterRoleVIP,
+		songrequests.ChatterRoleModerator,
+	},
+}
+
+func (a *App) permissionRule(key string) songrequests.PermissionRule {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if rule, ok := a.permissionRules[key]; ok {
+		return rule
+	}
+	return defaultPermissionRules[key]
+}
+
+func (a *App) setPermissionRule(key string, value string) error {
+	if _, ok := defaultPermissionRules[key]; !ok {
+		return errors.New("unknown permission " + key)
+	}
+	rule, err := songrequests.ParsePermissionRule(value)
+	if err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	a.permissionRules[key] = rule
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) setFollowerMinAge(value string) error {
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return errors.New("follower min age must be zero or more minutes")
+	}
+	a.settingsMu.Lock()
+	a.followerMinAge = time.Duration(minutes) * time.Minute
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) setSharedChatRequestMode(value string) error {
+	if value != songrequests.SharedChatRequestModeAllow && value != songrequests.SharedChatRequestModeDeny && value != songrequests.SharedChatRequestModeRestrict {
+		return errors.New("shared chat requests must be allow, deny or restrict")
+	}
+	a.settingsMu.Lock()
+	a.sharedChatRequests = value
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) sharedChatRequestMode() songrequests.SharedChatRequestMode {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if a.sharedChatRequests == "" {
+		return songrequests.SharedChatRequestModeDeny
+	}
+	return a.sharedChatRequests
+}
+
+func (a *App) permissionSettings() map[string]string {
+	sharedChatRequests := a.sharedChatRequestMode()
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	settings := map[string]string{
+		data.DB_KEY_FOLLOWER_MIN_AGE_MINUTES: strconv.Itoa(int(a.followerMinAge / time.Minute)),
+		data.DB_KEY_SHARED_CHAT_REQUESTS:     sharedChatRequests,
+	}
+	for k, v := range defaultPermissionRules {
+		if rule, ok := a.permissionRules[k]; ok {
+			v = rule
+		}
+		settings[k] = v.String()
+	}
+	return settings
+}
+
+// chatterAllowed checks the permission rule for key, the follow status is only looked up when nothing else grants access
+func (a *App) chatterAllowed(key string, roles *songrequests.ChatterRoles, chatterUserID string) bool {
+	return a.chatterAllowedByRule(a.permissionRule(key), roles, chatterUserID)
+}
+
+func (a *App) chatterAllowedByRule(rule songrequests.PermissionRule, roles *songrequests.ChatterRoles, chatterUserID string) bool {
+	if rule.NeedsFollowerCheck(*roles) {
+		isFollower, err := a.checkMainChannelFollower(chatterUserID)
+		if err != nil {
+			log.Println("Failed to check if chatter follows the channel", err)
+		} else {
+			roles.IsFollower = isFollower
+			roles.FollowerChecked = true
+		}
+	}
+	return rule.Allows(*roles)
+}
+
+type mainChannelFollow struct {
+	isFollower bool
+	followedAt time.Time
+}
+
+// Bounded so a busy chat cannot grow these forever, EventSub role changes invalidate entries early
+var mainChannelFollowCache = utils.NewLRUCache[string, mainChannelFollow](1000, time.Hour*2)
+var mainChannelUserRolesCache = utils.NewLRUCache[string, songrequests.ChatterRoles](1000, time.Hour*2)
+
+func (a *App) checkMainChannelFollower(chatterUserID string) (bool, error) {
+	a.settingsMu.RLock()
+	minAge := a.followerMinAge
+	a.settingsMu.RUnlock()
+
+	v, ok := mainChannelFollowCache.Get(chatterUserID)
+	if !ok {
+		// needs moderator:read:followers on the main account
+		resp, err := a.helix.GetChannelFollows(&helix.GetChannelFollowsParams{
+			BroadcasterID: a.twitchDataStruct.userID,
+			UserID:        chatterUserID,
+		})
+		if err != nil {
+			return false, err
+		}
+		if resp.ErrorMessage != "" {
+			return false, errors.New(resp.ErrorMessage)
+		}
+		v.isFollower = len(resp.Data.Channels) > 0
+		if v.isFollower {
+			v.followedAt = resp.Data.Channels[0].Followed.Time
+		}
+		mainChannelFollowCache.Set(chatterUserID, v)
+	}
+
+	return v.isFollower && !time.Now().Before(v.followedAt.Add(minAge)), nil
+}
+
+// checkMainChannelUserRoles asks Helix for the roles of a chatter in the main channel,
+// used when chat is read by the bot account and badges do not belong to the main channel
+func (a *App) checkMainChannelUserRoles(chatterUserID string) (songrequests.ChatterRoles, error) {
+	if roles, ok := mainChannelUserRolesCache.Get(chatterUserID); ok {
+		return roles, nil
+	}
+
+	roles := songrequests.ChatterRoles{}
+	realBroadcasterID := a.twitchDataStruct.userID
+
+	subsResponse, err := a.helix.GetSubscriptions(&helix.SubscriptionsParams{
+		UserID:        []string{chatterUserID},
+		BroadcasterID: realBroadcasterID,
+	})
+	if err != nil {
+		return roles, fmt.Errorf("check if %s is a subscriber: %w", chatterUserID, err)
+	}
+	if len(subsResponse.Data.Subscriptions) > 0 {
+		roles.IsSubscriber = true
+	}
+
+	modsResponse, err := a.helix.GetModerators(&helix.GetModeratorsParams{
+		UserIDs:       []string{chatterUserID},
+		BroadcasterID: realBroadcasterID,
+	})
+	if err != nil {
+		return roles, fmt.Errorf("check if %s is a moderator: %w", chatterUserID, err)
+	}
+	if len(modsResponse.Data.Moderators) > 0 {
+		roles.IsModerator = true
+	}
+
+	vipsResponse, err := a.helix.GetChannelVips(&helix.GetChannelVipsParams{
+		UserID:        chatterUserID,
+		BroadcasterID: realBroadcasterID,
+	})
+	if err != nil {
+		return roles, fmt.Errorf("check if %s is a VIP: %w", chatterUserID, err)
+	}
+	if len(vipsResponse.Data.ChannelsVips) > 0 {
+		roles.IsVIP = true
+	}
+
+	mainChannelUserRolesCache.Set(chatterUserID, roles)
+	return roles, nil
+}
+
+// sharedChatRequestAllowed applies the shared chat request mode to chatters of partner channels
+func (a *App) sharedChatRequestAllowed(roles *songrequests.ChatterRoles, chatterUserID string) bool {
+	switch a.sharedChatRequestMode() {
+	case songrequests.SharedChatRequestModeAllow:
+		return true
+	case songrequests.SharedChatRequestModeRestrict:
+		return a.chatterAllowed(data.DB_KEY_PERMISSION_SHARED_CHAT, roles, chatterUserID)
+	}
+	return false
+}
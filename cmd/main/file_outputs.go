@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/utils"
+)
+
+// Text files for OBS text sources, written into the file output dir, empty dir turns them off.
+// Templates accept {title}, {artist}, {link} and {requester}, the queue template also {position}
+var defaultFileOutputSettings = map[string]string{
+	data.DB_KEY_FILE_OUTPUT_DIR:         "",
+	data.DB_KEY_FILE_OUTPUT_NOW_PLAYING: "{title} - {artist}",
+	data.DB_KEY_FILE_OUTPUT_REQUESTER:   "Requested by {requester}",
+	data.DB_KEY_FILE_OUTPUT_QUEUE:       "{position}. {title} - {artist} ({requester})",
+	data.DB_KEY_FILE_OUTPUT_QUEUE_COUNT: "5",
+}
+
+const (
+	fileOutputNowPlaying = "now_playing.txt"
+	fileOutputRequester  = "requester.txt"
+	fileOutputQueue      = "queue.txt"
+	fileOutputArtwork    = "artwork.jpg"
+)
+
+func (a *App) fileOutputSetting(key string) string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if v, ok := a.fileOutputSettings[key]; ok {
+		return v
+	}
+	return defaultFileOutputSettings[key]
+}
+
+func checkFileOutputSetting(key string, value string) error {
+	if _, ok := defaultFileOutputSettings[key]; !ok {
+		return errors.New("unknown file output setting " + key)
+	}
+	switch key {
+	case data.DB_KEY_FILE_OUTPUT_DIR:
+		if value != "" && !filepath.IsAbs(value) && !filepath.IsLocal(value) {
+			return errors.New(key + " must be an absolute path or a folder inside the data directory")
+		}
+	case data.DB_KEY_FILE_OUTPUT_QUEUE_COUNT:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 50 {
+			return errors.New(key + " must be a number from 1 to 50")
+		}
+	}
+	return nil
+}
+
+func (a *App) setFileOutputSetting(key string, value string) error {
+	if err := checkFileOutputSetting(key, value); err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	a.fileOutputSettings[key] = value
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) fileOutputSettingsMap() map[string]string {
+	settings := map[string]string{}
+	for k := range defaultFileOutputSettings {
+		settings[k] = a.fileOutputSetting(k)
+	}
+	return settings
+}
+
+var fileOutputsMutex = sync.Mutex{}
+var fileOutputArtworkVideoID = ""
+
+// writeFileOutputs is called with the same request items the control panel gets
+func (a *App) writeFileOutputs(items []requestItem) {
+	dir := a.fileOutputSetting(data.DB_KEY_FILE_OUTPUT_DIR)
+	if dir == "" {
+		return
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(a.dataDir, dir)
+	}
+	fileOutputsMutex.Lock()
+	defer fileOutputsMutex.Unlock()
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		log.Println("Failed to create file output dir", err)
+		return
+	}
+
+	songQueueMutex.RLock()
+	song := playerInfo.Song
+	requestedBy := playerInfo.RequestedBy
+	songQueueMutex.RUnlock()
+
+	nowPlaying := ""
+	requester := ""
+	if song.VideoId != "" {
+		vars := map[string]string{
+			"title":     song.AlternativeTitle,
+			"artist":    song.Artist,
+			"link":      song.GetUrl(),
+			"requester": requestedBy,
+		}
+		nowPlaying = utils.ReplaceVars(a.fileOutputSetting(data.DB_KEY_FILE_OUTPUT_NOW_PLAYING), vars)
+		if requestedBy != "" {
+			requester = utils.ReplaceVars(a.fileOutputSetting(data.DB_KEY_FILE_OUTPUT_REQUESTER), vars)
+		}
+	}
+
+	count, _ := strconv.Atoi(a.fileOutputSetting(data.DB_KEY_FILE_OUTPUT_QUEUE_COUNT))
+	queueFormat := a.fileOutputSetting(data.DB_KEY_FILE_OUTPUT_QUEUE)
+	lines := []string{}
+	for _, v := range items {
+		if len(lines) >= count {
+			break
+		}
+		if v.Position == 0 || v.RequestedBy == "" {
+			continue
+		}
+		lines = append(lines, utils.ReplaceVars(queueFormat, map[string]string{
+			"position":  strconv.Itoa(len(lines) + 1),
+			"title":     v.Title,
+			"artist":    v.Artist,
+			"link":      v.Url,
+			"requester": v.RequestedBy,
+		}))
+	}
+
+	outputs := map[string]string{
+		fileOutputNowPlaying: nowPlaying,
+		fileOutputRequester:  requester,
+		fileOutputQueue:      strings.Join(lines, "\n"),
+	}
+	for name, content := range outputs {
+		err = utils.WriteFileAtomic(filepath.Join(dir, name), []byte(content))
+		if err != nil {
+			log.Println("Failed to write", name, err)
+		}
+	}
+
+	if song.ImageSrc != "" && song.VideoId != fileOutputArtworkVideoID {
+		err = writeArtworkFile(filepath.Join(dir, fileOutputArtwork), song.ImageSrc)
+		if err != nil {
+			log.Println("Failed to write", fileOutputArtwork, err)
+			return
+		}
+		fileOutputArtworkVideoID = song.VideoId
+	}
+}
+
+func writeArtworkFile(path string, imageUrl string) error {
+	client := http.Client{Timeout: time.Second * 10}
+	resp, err := client.Get(imageUrl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("artwork download status " + resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, b)
+}
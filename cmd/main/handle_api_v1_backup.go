@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/labstack/echo/v4"
+)
+
+func backupFileName(ext string) string {
+	return "pear-sr-" + time.Now().Format("2006-01-02-150405") + ext
+}
+
+// getExport downloads settings and history as a bundle, tokens only with include_tokens=true
+func (a *App) getExport(c echo.Context) error {
+	includeTokens, _ := strconv.ParseBool(c.QueryParam("include_tokens"))
+	bundle, err := a.store.Export(c.Request().Context(), store.ExportOptions{IncludeTokens: includeTokens})
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot export the database",
+		})
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+backupFileName(".json")+`"`)
+	return c.JSONPretty(http.StatusOK, bundle, "  ")
+}
+
+func (a *App) getHistoryCSV(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+backupFileName("-history.csv")+`"`)
+	c.Response().WriteHeader(http.StatusOK)
+	err := a.store.WriteHistoryCSV(c.Request().Context(), c.Response())
+	if err != nil {
+		// headers are gone already, the download just ends short
+		c.Logger().Error(err)
+	}
+	return nil
+}
+
+// getRedactedDatabase downloads a copy of the database file without tokens for bug reports
+func (a *App) getRedactedDatabase(c echo.Context) error {
+	dir, err := os.MkdirTemp("", "pear-sr-redacted")
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot create a temporary folder",
+		})
+	}
+	defer os.RemoveAll(dir)
+	name := backupFileName("-redacted.db")
+	path := filepath.Join(dir, name)
+	err = a.store.WriteRedactedCopy(c.Request().Context(), path)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot copy the database",
+		})
+	}
+	return c.Attachment(path, name)
+}
+
+// checkImportSettings runs the bundle settings through the same checks as a PATCH before anything is written,
+// keys this version does not know are dropped from the bundle and returned
+func (a *App) checkImportSettings(b *store.Bundle) ([]string, error) {
+	settings := map[string]string{}
+	kept := []store.BundleSetting{}
+	ignored := []string{}
+	for _, v := range b.Settings {
+		switch {
+		case data.IsSecretSetting(v.Key) || data.IsTokenKeySetting(v.Key):
+		case strings.HasPrefix(v.Key, data.DB_KEY_OVERLAY_PRESET_PREFIX):
+			name := strings.TrimPrefix(v.Key, data.DB_KEY_OVERLAY_PRESET_PREFIX)
+			preset := map[string]string{}
+			if !overlayPresetNameRegex.MatchString(name) || json.Unmarshal([]byte(v.Value), &preset) != nil {
+				return nil, errors.New(v.Key + " is not an overlay preset")
+			}
+			if err := checkOverlayPreset(preset); err != nil {
+				return nil, err
+			}
+		default:
+			if _, ok := findSettingDef(v.Key); !ok {
+				ignored = append(ignored, v.Key)
+				continue
+			}
+			settings[v.Key] = v.Value
+		}
+		kept = append(kept, v)
+	}
+	if err := a.validateSettings(settings); err != nil {
+		return nil, err
+	}
+	b.Settings = kept
+	return ignored, nil
+}
+
+// postImport merges a bundle, mode=skip keeps existing settings and mode=overwrite replaces them
+func (a *App) postImport(c echo.Context) error {
+	mode, err := store.ParseImportMode(c.QueryParam("mode"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	bundle := store.Bundle{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&bundle); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "body is not an export bundle",
+		})
+	}
+	ignoredSettings, err := a.checkImportSettings(&bundle)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "import failed, nothing was changed: " + err.Error(),
+		})
+	}
+	result, err := a.store.Import(c.Request().Context(), bundle, mode)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "import failed, nothing was changed: " + err.Error(),
+		})
+	}
+
+	restartRequired := false
+	for _, k := range result.ChangedSettings {
+		if data.IsSecretSetting(k) {
+			restartRequired = true
+			continue
+		}
+		v, err := a.store.Settings.Get(c.Request().Context(), k)
+		if err != nil {
+			continue
+		}
+		if _, err := a.applySetting(k, v); err != nil {
+			c.Logger().Warn("imported setting " + k + " was not applied: " + err.Error())
+		}
+	}
+	if publicQueueListenerChanged(result.ChangedSettings) {
+		go a.restartPublicQueueServer()
+	}
+	statsCache.Purge()
+	userStatsCache.Purge()
+	resetSongBlockers()
+	return c.JSON(http.StatusOK, echo.Map{
+		"result":           result,
+		"ignored_settings": ignoredSettings,
+		"restart_required": restartRequired,
+	})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/labstack/echo/v4"
+)
+
+type historyItem struct {
+	ID            int32     `json:"id"`
+	VideoID       string    `json:"video_id"`
+	Title         string    `json:"title"`
+	Artist        string    `json:"artist"`
+	ImageUrl      string    `json:"image_url"`
+	Url           string    `json:"url"`
+	RequestedBy   string    `json:"requested_by"`
+	RequestedByID string    `json:"requested_by_id"`
+	RequestedAt   time.Time `json:"requested_at"`
+	Source        string    `json:"source"`
+	Outcome       string    `json:"outcome"`
+	OutcomeReason string    `json:"outcome_reason,omitempty"`
+}
+
+type historySong struct {
+	VideoID         string    `json:"video_id"`
+	Title           string    `json:"title"`
+	Artist          string    `json:"artist"`
+	ImageUrl        string    `json:"image_url"`
+	Url             string    `json:"url"`
+	Count           int       `json:"count"`
+	LastRequestedAt time.Time `json:"last_requested_at"`
+}
+
+// parseHistoryDate takes 2006-01-02 or RFC3339, a plain date for the end of a range includes that whole day
+func parseHistoryDate(s string, endOfDay bool) (time.Time, error) {
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// getHistory lists past requests newest first, filtered by requester, artist, text in title or artist,
+// outcome and date range, with request counts per song over the same filter leaving out rejected ones
+func (a *App) getHistory(c echo.Context) error {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.QueryParam("per_page"))
+	if err != nil || perPage < 1 || perPage > 200 {
+		perPage = 50
+	}
+	from := time.Time{}
+	if v := c.QueryParam("from"); v != "" {
+		from, err = parseHistoryDate(v, false)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "from must be a date like 2006-01-02 or RFC3339",
+			})
+		}
+	}
+	to := time.Time{}
+	if v := c.QueryParam("to"); v != "" {
+		to, err = parseHistoryDate(v, true)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "to must be a date like 2006-01-02 or RFC3339",
+			})
+		}
+	}
+
+	ctx := c.Request().Context()
+	filter := store.HistoryFilter{
+		Requester: c.QueryParam("requester"),
+		Artist:    c.QueryParam("artist"),
+		Query:     c.QueryParam("q"),
+		Outcome:   c.QueryParam("outcome"),
+		From:      from,
+		To:        to,
+		Limit:     perPage,
+		Offset:    (page - 1) * perPage,
+	}
+	rows, err := a.store.Requests.History(ctx, filter)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read request history",
+		})
+	}
+	total, err := a.store.Requests.CountHistory(ctx, filter)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read request history",
+		})
+	}
+	songs, err := a.store.Requests.HistorySongs(ctx, filter, perPage)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read request history",
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"items":    historyItems(rows),
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+		"songs":    historySongs(songs),
+	})
+}
+
+func historyItems(rows []store.HistoryRow) []historyItem {
+	items := []historyItem{}
+	for _, v := range rows {
+		// rows from before timestamps were stored as UTC can fail to parse
+		requestedAt, _ := time.Parse(time.RFC3339, v.RequestedAt)
+		items = append(items, historyItem{
+			ID:            v.ID,
+			VideoID:       v.VideoID,
+			Title:         v.SongTitle,
+			Artist:        v.ArtistName,
+			ImageUrl:      v.ImageURL,
+			Url:           "https://youtu.be/" + v.VideoID,
+			RequestedBy:   v.TwitchUsername,
+			RequestedByID: v.TwitchUserID,
+			RequestedAt:   requestedAt,
+			Source:        v.Source,
+			Outcome:       v.Outcome,
+			OutcomeReason: v.OutcomeReason,
+		})
+	}
+	return items
+}
+
+func historySongs(songs []store.HistorySong) []historySong {
+	items := []historySong{}
+	for _, v := range songs {
+		lastRequestedAt, _ := time.Parse(time.RFC3339, v.LastRequestedAt)
+		items = append(items, historySong{
+			VideoID:         v.VideoID,
+			Title:           v.SongTitle,
+			Artist:          v.ArtistName,
+			ImageUrl:        v.ImageURL,
+			Url:             "https://youtu.be/" + v.VideoID,
+			Count:           int(v.Count),
+			LastRequestedAt: lastRequestedAt,
+		})
+	}
+	return items
+}
+
+// requeueHistory queues the song of a past request again in the name of the broadcaster,
+// :id is the numeric id of the history item
+func (a *App) requeueHistory(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "id must be the number of a history item",
+		})
+	}
+	if !a.twitchDataStruct.isAuthenticated {
+		return c.JSON(http.StatusConflict, echo.Map{
+			"error": "connect the Twitch main account first",
+		})
+	}
+
+	result, err := a.store.Requests.Request(c.Request().Context(), int32(id))
+	if err == store.ErrNotFound {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": "request not found in request history",
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read request history",
+		})
+	}
+
+	queue, err := songrequests.GetPearQueue()
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusBadGateway, echo.Map{
+			"error": "cannot get queue from Pear Desktop",
+		})
+	}
+	if queue.IndexAfterSelected(result.VideoID) != -1 {
+		return c.JSON(http.StatusConflict, echo.Map{
+			"error": "song is already in queue",
+		})
+	}
+
+	song := &songrequests.SongResult{
+		VideoID:  result.VideoID,
+		Title:    result.SongTitle,
+		Artist:   result.ArtistName,
+		ImageUrl: result.ImageURL,
+	}
+	// no MessageId marks it as queued from the control panel
+	event := twitch.EventChannelChatMessage{}
+	event.BroadcasterUserId = a.twitchDataStruct.userID
+	event.BroadcasterUserLogin = a.twitchDataStruct.login
+	event.ChatterUserId = a.twitchDataStruct.userID
+	event.ChatterUserLogin = a.twitchDataStruct.login
+	go func() {
+		srChan <- struct {
+			song       *songrequests.SongResult
+			event      twitch.EventChannelChatMessage
+			usedCredit bool
+		}{
+			song:  song,
+			event: event,
+		}
+	}()
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type playItem struct {
+	ID              int32     `json:"id"`
+	VideoID         string    `json:"video_id"`
+	Title           string    `json:"title"`
+	Artist          string    `json:"artist"`
+	ImageUrl        string    `json:"image_url"`
+	Url             string    `json:"url"`
+	DurationSeconds int32     `json:"duration_seconds"`
+	StartedAt       time.Time `json:"started_at"`
+	EndedAt         time.Time `json:"ended_at,omitzero"`
+	PlayedSeconds   int32     `json:"played_seconds"`
+	Skipped         bool      `json:"skipped"`
+	SkippedBy       string    `json:"skipped_by,omitempty"`
+	RequestID       *int32    `json:"request_id"`
+}
+
+// getPlays is the setlist for a time range oldest first, the last 24 hours when no range is given,
+// with how many of those plays were skipped
+func (a *App) getPlays(c echo.Context) error {
+	var err error
+	from := time.Now().Add(-24 * time.Hour)
+	to := time.Time{}
+	if v := c.QueryParam("from"); v != "" {
+		from, err = parseHistoryDate(v, false)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "from must be a date like 2006-01-02 or RFC3339",
+			})
+		}
+	}
+	if v := c.QueryParam("to"); v != "" {
+		to, err = parseHistoryDate(v, true)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": "to must be a date like 2006-01-02 or RFC3339",
+			})
+		}
+	}
+
+	ctx := c.Request().Context()
+	plays, err := a.store.Plays.List(ctx, from, to)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read play history",
+		})
+	}
+	summary, err := a.store.Plays.Summary(ctx, from, to)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read play history",
+		})
+	}
+	items := []playItem{}
+	for _, v := range plays {
+		startedAt, _ := time.Parse(time.RFC3339, v.StartedAt)
+		endedAt, _ := time.Parse(time.RFC3339, v.EndedAt)
+		id := int32(0)
+		if v.ID != nil {
+			id = *v.ID
+		}
+		items = append(items, playItem{
+			ID:              id,
+			VideoID:         v.VideoID,
+			Title:           v.SongTitle,
+			Artist:          v.ArtistName,
+			ImageUrl:        v.ImageURL,
+			Url:             "https://youtu.be/" + v.VideoID,
+			DurationSeconds: v.DurationSeconds,
+			StartedAt:       startedAt,
+			EndedAt:         endedAt,
+			PlayedSeconds:   v.PlayedSeconds,
+			Skipped:         v.Skipped,
+			SkippedBy:       v.SkippedBy,
+			RequestID:       v.RequestID,
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"items":     items,
+		"total":     summary.Plays,
+		"requested": summary.Requested,
+		"skipped":   summary.Skipped,
+		"skip_rate": summary.SkipRate(),
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/labstack/echo/v4"
+)
+
+type requestItem struct {
+	ID string `json:"id"`
+	// Index in the Pear Desktop queue, Position is relative to the song playing now
+	Index       int    `json:"index"`
+	Position    int    `json:"position"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Length      string `json:"length"`
+	ImageUrl    string `json:"image_url"`
+	Url         string `json:"url"`
+	RequestedBy string `json:"requested_by,omitempty"`
+	// RequestedByID is what BAN_USER takes from the control panel
+	RequestedByID string     `json:"requested_by_id,omitempty"`
+	RequestedAt   *time.Time `json:"requested_at,omitempty"`
+	Source        string     `json:"source,omitempty"`
+}
+
+// getRequestItems merges the Pear Desktop queue from the song playing now with who requested what
+func (a *App) getRequestItems() ([]requestItem, error) {
+	queue, err := songrequests.GetPearQueue()
+	if err != nil {
+		return nil, err
+	}
+
+	requests := map[string]songQueueItem{}
+	songQueueMutex.RLock()
+	for _, v := range songQueue {
+		requests[v.song.VideoID] = v
+	}
+	songQueueMutex.RUnlock()
+
+	nowIndex := queue.SelectedIndex()
+	if nowIndex == -1 {
+		nowIndex = 0
+	}
+	items := []requestItem{}
+	for i := nowIndex; i < len(queue.Items); i++ {
+		v := queue.Items[i]
+		item := requestItem{
+			ID:       v.VideoID(),
+			Index:    i,
+			Position: i - nowIndex,
+			Title:    v.Title(),
+			Artist:   v.Artist(),
+			Length:   v.Length(),
+			ImageUrl: v.ImageUrl(),
+			Url:      "https://youtu.be/" + v.VideoID(),
+		}
+		if r, ok := requests[item.ID]; ok && i != nowIndex {
+			requestedAt := r.requestedAt
+			item.RequestedBy = r.requestedBy
+			item.RequestedByID = r.requestedByID
+			item.RequestedAt = &requestedAt
+			item.Source = r.source
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (a *App) broadcastRequests() {
+	items, err := a.getRequestItems()
+	if err != nil {
+		log.Println("Failed to get requests for control panel", err)
+		return
+	}
+	a.broadcastSplit(queueUpdatedEvent(items), queueUpdatedEvent(publicRequestItems(items)))
+	a.writeFileOutputs(items)
+}
+
+func queueUpdatedEvent(items []requestItem) string {
+	return appWsEvent(appWsEventQueueUpdated, echo.Map{
+		"requests": items,
+	})
+}
+
+// publicRequestItems keeps what overlays show, the requester IDs and request details stay in the control panel
+func publicRequestItems(items []requestItem) []requestItem {
+	public := make([]requestItem, len(items))
+	for i, v := range items {
+		v.RequestedByID = ""
+		v.RequestedAt = nil
+		v.Source = ""
+		public[i] = v
+	}
+	return public
+}
+
+func (a *App) getRequests(c echo.Context) error {
+	items, err := a.getRequestItems()
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusBadGateway, echo.Map{
+			"error": "cannot get queue from Pear Desktop",
+		})
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+func (a *App) deleteRequest(c echo.Context) error {
+	err := a.removeRequest(c.Param("id"))
+	if errors.Is(err, errRequestNotFound) {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusBadGateway, echo.Map{
+			"error": "cannot remove song from Pear Desktop queue",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a *App) patchRequest(c echo.Context) error {
+	videoID := c.Param("id")
+	body := c.Request().Body
+	rawBodyData, err := io.ReadAll(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "read request body",
+		})
+	}
+	defer body.Close()
+
+	// 1 is the song after the one playing now
+	move := struct {
+		Position int `json:"position"`
+	}{}
+	err = json.Unmarshal(rawBodyData, &move)
+	if err != nil || move.Position < 1 {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "position must be 1 or more",
+		})
+	}
+
+	err = a.moveRequest(videoID, move.Position)
+	if errors.Is(err, errRequestNotFound) {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusBadGateway, echo.Map{
+			"error": "cannot move song in Pear Desktop queue",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getStats is the same data the chat stats commands use, plus how often plays were skipped
+func (a *App) getStats(c echo.Context) error {
+	period := c.QueryParam("period")
+	snapshot, err := a.statsSnapshot(period)
+	if err == errUnknownStatsPeriod {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read stats",
+		})
+	}
+
+	plays, err := a.store.Plays.Summary(c.Request().Context(), statsPeriods[snapshot.Period].since(), time.Time{})
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read play history",
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"period":         snapshot.Period,
+		"label":          snapshot.Label,
+		"top_songs":      snapshot.TopSongs,
+		"top_artists":    snapshot.TopArtists,
+		"top_requesters": snapshot.TopRequesters,
+		"plays":          plays.Plays,
+		"skipped":        plays.Skipped,
+		"skip_rate":      plays.SkipRate(),
+	})
+}
+
+// getUserStats is !mystats for any requester, login also finds requests saved before user ids were kept
+func (a *App) getUserStats(c echo.Context) error {
+	stats, label, err := a.userStats(c.Param("id"), c.QueryParam("login"), c.QueryParam("period"))
+	if err == errUnknownStatsPeriod {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read stats",
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"label":    label,
+		"requests": stats.Requests,
+		"played":   stats.Played,
+		"skipped":  stats.Skipped,
+		"top_song": stats.TopSong,
+	})
+}
@@ -1,6 +1,5 @@
 package main
 
-//lint:file-ignore ST1001 Dot imports by jet
 import (
 	"encoding/json"
 	"errors"
@@ -8,11 +7,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
-	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
-	. "github.com/go-jet/jet/v2/sqlite"
 	"github.com/labstack/echo/v4"
 	"github.com/nicklaw5/helix/v2"
 )
@@ -40,7 +35,13 @@ func (a *App) processTwitchOAuth(c echo.Context) error {
 			"error": "parse request body",
 		})
 	}
-	tokenForBot := authData.State == "bot"
+	state, ok := takeOAuthState(authData.State)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "OAuth state is invalid or expired, try connecting again",
+		})
+	}
+	tokenForBot := state.forBot
 
 	if authData.TokenType != "bearer" {
 		return c.JSON(http.StatusBadRequest, echo.Map{
@@ -73,15 +74,6 @@ func (a *App) processTwitchOAuth(c echo.Context) error {
 		})
 	}
 	t = t.Add(time.Duration(expiresIn) * time.Second)
-	db, err := databaseconn.NewDBConnection()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{
-			"error": "cannot save token in database",
-		})
-	}
-	defer func() {
-		db.Close()
-	}()
 	selectedTwitchDataStruct := a.twitchDataStruct
 	if tokenForBot {
 		if a.twitchDataStruct.login == "" {
@@ -115,15 +107,7 @@ func (a *App) processTwitchOAuth(c echo.Context) error {
 		}
 	}
 
-	newToken := model.Settings{
-		Key:   dbSaveKey,
-		Value: authData.AccessToken,
-	}
-	stmt := Settings.INSERT(Settings.AllColumns).MODEL(newToken).ON_CONFLICT(Settings.Key).DO_UPDATE(SET(
-		Settings.Value.SET(String(authData.AccessToken)),
-	))
-
-	_, err = stmt.ExecContext(c.Request().Context(), db)
+	err = a.store.Settings.Set(c.Request().Context(), dbSaveKey, authData.AccessToken)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"error": "Failed to save token in database",
@@ -132,14 +116,18 @@ func (a *App) processTwitchOAuth(c echo.Context) error {
 	b := echo.Map{
 		"type":            "TWITCH_INFO",
 		"stream_online":   a.streamOnline,
-		"reward_id":       a.songRequestRewardID,
+		"reward_id":       a.rewardID(),
 		"login":           a.twitchDataStruct.login,
 		"login_bot":       a.twitchDataStructBot.login,
 		"expiry_date":     a.twitchDataStruct.expiresDate.Local().Format(data.TWITCH_SERVER_DATE_LAYOUT),
 		"expiry_date_bot": a.twitchDataStructBot.expiresDate.Local().Format(data.TWITCH_SERVER_DATE_LAYOUT),
+		"permissions":     a.permissionSettings(),
+		"credits":         a.creditSettingsMap(),
+		"now_playing":     a.nowPlayingSettingsMap(),
+		"file_output":     a.fileOutputSettingsMap(),
 	}
 	bb, _ := json.Marshal(b)
-	a.clientsBroadcast <- string(bb)
+	a.broadcastAuthenticated(string(bb))
 	return c.NoContent(http.StatusOK)
 
 }
@@ -1,16 +1,13 @@
 package main
 
-//lint:file-ignore ST1001 Dot imports by jet
 import (
 	"encoding/json"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
 
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
-	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
-	. "github.com/go-jet/jet/v2/sqlite"
 	"github.com/labstack/echo/v4"
 )
 
@@ -32,38 +29,50 @@ func (a *App) processTwitchSettings(c echo.Context) error {
 			"error": "parse request body",
 		})
 	}
-	db, err := databaseconn.NewDBConnection()
-	if err != nil {
+	// check everything first so a bad value does not leave half the settings applied
+	if err := a.validateSettings(settings); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// save everything in one go before applying, a failed save changes nothing
+	if err := a.store.Settings.SetMany(c.Request().Context(), settings); err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
 			"error": "save data failed",
 		})
 	}
-	defer db.Close()
 	for k, v := range settings {
-		if k == data.DB_KEY_TWITCH_SONG_REQUEST_REWARD_ID {
-			newSetting := model.Settings{
-				Key:   data.DB_KEY_TWITCH_SONG_REQUEST_REWARD_ID,
-				Value: v,
-			}
-			stmt := Settings.INSERT(Settings.AllColumns).MODEL(newSetting).ON_CONFLICT(Settings.Key).DO_UPDATE(SET(
-				Settings.Value.SET(String(v)),
-			))
-			stmt.ExecContext(c.Request().Context(), db)
-			a.songRequestRewardID = v
+		if _, err := a.applySetting(k, v); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{
+				"error": err.Error(),
+			})
 		}
 	}
+	if publicQueueListenerChanged(slices.Collect(maps.Keys(settings))) {
+		go a.restartPublicQueueServer()
+	}
 
 	b := echo.Map{
 		"type":            "TWITCH_INFO",
 		"stream_online":   a.streamOnline,
-		"reward_id":       a.songRequestRewardID,
+		"reward_id":       a.rewardID(),
 		"login":           a.twitchDataStruct.login,
 		"login_bot":       a.twitchDataStructBot.login,
 		"expiry_date":     a.twitchDataStruct.expiresDate.Local().Format(data.TWITCH_SERVER_DATE_LAYOUT),
 		"expiry_date_bot": a.twitchDataStructBot.expiresDate.Local().Format(data.TWITCH_SERVER_DATE_LAYOUT),
+		"permissions":     a.permissionSettings(),
+		"credits":         a.creditSettingsMap(),
+		"now_playing":     a.nowPlayingSettingsMap(),
+		"file_output":     a.fileOutputSettingsMap(),
 	}
 	bb, _ := json.Marshal(b)
-	a.clientsBroadcast <- string(bb)
+	a.broadcastAuthenticated(string(bb))
 	return c.NoContent(http.StatusOK)
 
 }
+
+func (a *App) getSettings(c echo.Context) error {
+	return c.JSON(http.StatusOK, a.settingValues())
+}
@@ -3,6 +3,8 @@ package main
 //lint:file-ignore ST1001 Dot imports by jet
 import (
 	"encoding/json"
+	"sync/atomic"
+	"time"
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
 	"github.com/labstack/echo/v4"
@@ -10,17 +12,13 @@ import (
 )
 
 func (a *App) handleAppWs(c echo.Context) error {
+	authenticated := a.hasSession(c)
+	lastHeard := &atomic.Int64{}
+	lastHeard.Store(time.Now().UnixNano())
 	websocket.Handler(func(ws *websocket.Conn) {
-		// Add client to the map
-		a.clientsMu.Lock()
-		a.clients[ws] = struct{}{}
-		a.clientsMu.Unlock()
-
-		defer func() {
-			a.clientsMu.Lock()
-			delete(a.clients, ws)
-			a.clientsMu.Unlock()
-		}()
+		client := newWsClient(ws, authenticated, lastHeard)
+		go client.writeLoop()
+		defer client.close()
 
 		// Send initial info
 		// only login and expiry date
@@ -37,16 +35,40 @@ func (a *App) handleAppWs(c echo.Context) error {
 		infoOnConnect, _ := json.Marshal(echo.Map{
 			"type":            "TWITCH_INFO",
 			"stream_online":   a.streamOnline,
-			"reward_id":       a.songRequestRewardID,
+			"reward_id":       a.rewardID(),
 			"login":           a.twitchDataStruct.login,
 			"login_bot":       a.twitchDataStructBot.login,
 			"expiry_date":     expiryDate,
 			"expiry_date_bot": expiryDateBot,
+			"permissions":     a.permissionSettings(),
+			"credits":         a.creditSettingsMap(),
+			"now_playing":     a.nowPlayingSettingsMap(),
+			"file_output":     a.fileOutputSettingsMap(),
 		})
-		err := websocket.Message.Send(ws, string(infoOnConnect))
-		if err != nil {
-			// conn already closed
-			return
+		if authenticated {
+			client.queue(string(infoOnConnect))
+			client.queue(a.requestsState())
+		}
+		songQueueMutex.RLock()
+		client.queue(nowPlayingEvent())
+		songQueueMutex.RUnlock()
+
+		// Add client to the map after the initial info so broadcasts come after it
+		a.clientsMu.Lock()
+		a.clients[client] = struct{}{}
+		a.clientsMu.Unlock()
+
+		defer func() {
+			a.clientsMu.Lock()
+			delete(a.clients, client)
+			a.clientsMu.Unlock()
+		}()
+
+		if items, err := a.getRequestItems(); err == nil {
+			if !authenticated {
+				items = publicRequestItems(items)
+			}
+			client.queue(queueUpdatedEvent(items))
 		}
 
 		// Keep connection alive and handle any incoming messages
@@ -57,8 +79,14 @@ func (a *App) handleAppWs(c echo.Context) error {
 				// This break marks the ws closure
 				break
 			}
-			// We don't handle incoming messages from frontend ever
+			reply := `{"type":"ERROR","error":"read only connection, open the control panel from the link in the app console"}`
+			if authenticated {
+				reply = a.handleAppWsCommand(msg)
+			}
+			if !client.queue(reply) {
+				break
+			}
 		}
-	}).ServeHTTP(c.Response(), c.Request())
+	}).ServeHTTP(heardResponseWriter{ResponseWriter: c.Response(), lastHeard: lastHeard}, c.Request())
 	return nil
 }
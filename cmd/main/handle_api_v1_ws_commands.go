@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Commands the control panel sends over the app websocket, every command is answered
+// with an ACK or ERROR carrying the same id
+type appWsCommand struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	VideoID   string `json:"video_id"`
+	Position  int    `json:"position"`
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	PendingID string `json:"pending_id"`
+}
+
+const (
+	appWsCommandSkip           = "SKIP"
+	appWsCommandRemove         = "REMOVE"
+	appWsCommandMove           = "MOVE"
+	appWsCommandBanUser        = "BAN_USER"
+	appWsCommandOpenRequests   = "OPEN_REQUESTS"
+	appWsCommandCloseRequests  = "CLOSE_REQUESTS"
+	appWsCommandApprovePending = "APPROVE_PENDING"
+	appWsCommandRejectPending  = "REJECT_PENDING"
+)
+
+func (a *App) handleAppWsCommand(msg string) string {
+	cmd := appWsCommand{}
+	err := json.Unmarshal([]byte(msg), &cmd)
+	if err != nil {
+		b, _ := json.Marshal(echo.Map{
+			"type":  "ERROR",
+			"error": "invalid command",
+		})
+		return string(b)
+	}
+
+	err = a.runAppWsCommand(cmd)
+	if err != nil {
+		log.Println("Control panel command", cmd.Type, "failed", err)
+		b, _ := json.Marshal(echo.Map{
+			"type":    "ERROR",
+			"id":      cmd.ID,
+			"command": cmd.Type,
+			"error":   err.Error(),
+		})
+		return string(b)
+	}
+	b, _ := json.Marshal(echo.Map{
+		"type":    "ACK",
+		"id":      cmd.ID,
+		"command": cmd.Type,
+	})
+	return string(b)
+}
+
+func (a *App) runAppWsCommand(cmd appWsCommand) error {
+	switch cmd.Type {
+	case appWsCommandSkip:
+		_, err := a.skipSong(a.twitchDataStruct.login)
+		return err
+	case appWsCommandRemove:
+		return a.removeRequest(cmd.VideoID)
+	case appWsCommandMove:
+		return a.moveRequest(cmd.VideoID, cmd.Position)
+	case appWsCommandBanUser:
+		return a.banRequester(cmd.UserID, cmd.UserLogin)
+	case appWsCommandOpenRequests:
+		a.setRequestsOpen(true)
+		return nil
+	case appWsCommandCloseRequests:
+		a.setRequestsOpen(false)
+		return nil
+	case appWsCommandApprovePending:
+		return a.approvePendingRequest(cmd.PendingID)
+	case appWsCommandRejectPending:
+		return a.rejectPendingRequest(cmd.PendingID)
+	}
+	return errors.New("unknown command " + cmd.Type)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/labstack/echo/v4"
+)
+
+// OBS browser sources, driven by the app websocket events
+//
+//go:embed overlays/*
+var overlayFS embed.FS
+
+var overlayPages = map[string]string{
+	"now-playing":   "overlays/now-playing.html",
+	"queue":         "overlays/queue.html",
+	"request-toast": "overlays/request-toast.html",
+}
+
+var overlayAssets = map[string]string{
+	"overlay.css": "overlays/overlay.css",
+	"overlay.js":  "overlays/overlay.js",
+}
+
+// Presets are saved as settings rows, key prefix and preset name, value a JSON object of these options.
+// Same names overlay.js reads from query params
+var overlayPresetKeys = map[string]struct{}{
+	"layout":    {},
+	"accent":    {},
+	"bg":        {},
+	"text":      {},
+	"font":      {},
+	"size":      {},
+	"radius":    {},
+	"progress":  {},
+	"artwork":   {},
+	"requester": {},
+	"max":       {},
+	"duration":  {},
+}
+
+var overlayPresetNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+func checkOverlayPreset(preset map[string]string) error {
+	for k, v := range preset {
+		if _, ok := overlayPresetKeys[k]; !ok {
+			return errors.New("unknown overlay option " + k)
+		}
+		if len(v) > 100 {
+			return errors.New("overlay option " + k + " is too long")
+		}
+	}
+	return nil
+}
+
+func (a *App) handleOverlayPage(c echo.Context) error {
+	file, ok := overlayPages[c.Param("name")]
+	if !ok {
+		return echo.ErrNotFound
+	}
+	return echo.StaticFileHandler(file, overlayFS)(c)
+}
+
+func (a *App) handleOverlayAsset(c echo.Context) error {
+	file, ok := overlayAssets[c.Param("file")]
+	if !ok {
+		return echo.ErrNotFound
+	}
+	return echo.StaticFileHandler(file, overlayFS)(c)
+}
+
+func (a *App) getOverlayPresets(c echo.Context) error {
+	results, err := a.store.Settings.ListPrefix(c.Request().Context(), data.DB_KEY_OVERLAY_PRESET_PREFIX)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read overlay presets",
+		})
+	}
+	presets := map[string]map[string]string{}
+	for _, v := range results {
+		preset := map[string]string{}
+		if json.Unmarshal([]byte(v.Value), &preset) == nil {
+			presets[strings.TrimPrefix(v.Key, data.DB_KEY_OVERLAY_PRESET_PREFIX)] = preset
+		}
+	}
+	return c.JSON(http.StatusOK, presets)
+}
+
+func (a *App) getOverlayPreset(c echo.Context) error {
+	value, err := a.store.Settings.Get(c.Request().Context(), data.DB_KEY_OVERLAY_PRESET_PREFIX+c.Param("name"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{
+			"error": "overlay preset not found",
+		})
+	}
+	return c.JSONBlob(http.StatusOK, []byte(value))
+}
+
+func (a *App) putOverlayPreset(c echo.Context) error {
+	name := c.Param("name")
+	if !overlayPresetNameRegex.MatchString(name) {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "preset name must be 1 to 32 of a-z, 0-9, _ and -",
+		})
+	}
+	body := c.Request().Body
+	rawBodyData, err := io.ReadAll(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "read request body",
+		})
+	}
+	defer body.Close()
+
+	preset := map[string]string{}
+	err = json.Unmarshal(rawBodyData, &preset)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": "parse request body",
+		})
+	}
+	if err := checkOverlayPreset(preset); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"error": err.Error(),
+		})
+	}
+
+	b, _ := json.Marshal(preset)
+	err = a.store.Settings.Set(c.Request().Context(), data.DB_KEY_OVERLAY_PRESET_PREFIX+name, string(b))
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "save data failed",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a *App) deleteOverlayPreset(c echo.Context) error {
+	err := a.store.Settings.Delete(c.Request().Context(), data.DB_KEY_OVERLAY_PRESET_PREFIX+c.Param("name"))
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "delete data failed",
+		})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
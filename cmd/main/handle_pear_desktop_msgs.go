@@ -3,7 +3,6 @@ package main
 import (
 	"log"
 
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
 	"github.com/valyala/fastjson"
 )
 
@@ -24,7 +23,10 @@ func (a *App) handlePearDesktopMsgs() {
 			case "POSITION_CHANGED":
 				songQueueMutex.Lock()
 				playerInfo.Position = v.GetInt("position")
+				ev := positionEvent()
 				songQueueMutex.Unlock()
+				setCurrentPlayPosition(v.GetInt("position"))
+				a.broadcast(ev)
 			case "PLAYER_INFO":
 				songQueueMutex.Lock()
 				playerInfo.IsPlaying = v.GetBool("isPlaying")
@@ -37,12 +39,22 @@ func (a *App) handlePearDesktopMsgs() {
 					VideoId:          string(v.GetStringBytes("song", "videoId")),
 				}
 				playerInfo.Song = songinfo
+				ev := nowPlayingEvent()
 				songQueueMutex.Unlock()
+				a.broadcast(ev)
+				go a.broadcastRequests()
+				// sent on connect, a song already playing then was not seen starting
+				if currentPlayVideoID() != songinfo.VideoId {
+					a.startPlay(songinfo, false)
+					setCurrentPlayPosition(v.GetInt("position"))
+				}
 			case "VIDEO_CHANGED":
 				songQueueMutex.Lock()
 				newVideoId := string(v.GetStringBytes("song", "videoId"))
 				playerInfo.Position = v.GetInt("position")
-				if playerInfo.Song.VideoId != newVideoId {
+				changed := playerInfo.Song.VideoId != newVideoId
+				isRequest := false
+				if changed {
 					songinfo := playerSonginfo{
 						ImageSrc:         string(v.GetStringBytes("song", "imageSrc")),
 						Artist:           string(v.GetStringBytes("song", "artist")),
@@ -53,22 +65,36 @@ func (a *App) handlePearDesktopMsgs() {
 					playerInfo.Song = songinfo
 					if len(songQueue) > 1 && songQueue[0].song.VideoID != newVideoId {
 						// queue invalid now, wiping queue
-						songQueue = []struct {
-							requestedBy string
-							song        songrequests.SongResult
-						}{}
+						songQueue = []songQueueItem{}
 						log.Println("queue was wiped because it was out of sync with Pear Desktop")
 					}
+					requestedBy := ""
+					if len(songQueue) > 0 && songQueue[0].song.VideoID == newVideoId {
+						requestedBy = songQueue[0].requestedBy
+						isRequest = true
+					}
+					playerInfo.RequestedBy = requestedBy
+					go a.announceNowPlaying(songinfo, requestedBy)
 					if len(songQueue) > 0 {
 						songQueue = songQueue[1:]
 					}
+					ev := nowPlayingEvent()
+					a.broadcast(ev)
+					go a.broadcastRequests()
 				}
+				song := playerInfo.Song
 				songQueueMutex.Unlock()
+				if changed {
+					a.startPlay(song, isRequest)
+				}
 			case "PLAYER_STATE_CHANGED":
 				songQueueMutex.Lock()
 				playerInfo.Position = v.GetInt("position")
 				playerInfo.IsPlaying = v.GetBool("isPlaying")
+				ev := positionEvent()
 				songQueueMutex.Unlock()
+				setCurrentPlayPosition(v.GetInt("position"))
+				a.broadcast(ev)
 			default:
 				// Nothing, ignore non important
 			}
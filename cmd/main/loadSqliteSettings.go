@@ -1,32 +1,17 @@
 package main
 
-//lint:file-ignore ST1001 Dot imports by jet
 import (
 	"errors"
+	"log"
 	"net/http"
 	"time"
 
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
 	"github.com/nicklaw5/helix/v2"
-
-	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
-	. "github.com/go-jet/jet/v2/sqlite"
 )
 
 func (a *App) loadSqliteSettings() error {
-	db, err := databaseconn.NewDBConnection()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		db.Close()
-	}()
-
-	results := []model.Settings{}
-	stmt := SELECT(Settings.Key, Settings.Value).FROM(Settings)
-	err = stmt.QueryContext(a.ctx, db, &results)
+	results, err := a.store.Settings.All(a.ctx)
 	if err != nil {
 		return err
 	}
@@ -35,13 +20,18 @@ func (a *App) loadSqliteSettings() error {
 		if result.Key == data.DB_KEY_TWITCH_ACCESS_TOKEN {
 			a.twitchDataStruct.accessToken = result.Value
 		}
-		if result.Key == data.DB_KEY_TWITCH_SONG_REQUEST_REWARD_ID {
-			a.songRequestRewardID = result.Value
-		}
 		if result.Key == data.DB_KEY_TWITCH_ACCESS_TOKEN_BOT {
 			a.twitchDataStructBot.accessToken = result.Value
 		}
+		if result.Key == data.DB_KEY_SESSION_SECRET {
+			a.sessionSecret = result.Value
+		}
+		if handled, err := a.applySetting(result.Key, result.Value); handled && err != nil {
+			log.Println("Ignoring invalid saved setting", result.Key, err)
+		}
 	}
+	a.setNowPlayingThisStream(a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_ENABLED) == "true")
+	a.restartPublicQueueServer()
 
 	if a.twitchDataStruct.accessToken != "" {
 		isValid, response, err := a.helix.ValidateToken(a.twitchDataStruct.accessToken)
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	logFileName = "pear-desktop-twitch-song-requests.log"
+	// the previous log is kept once as .1 when it grows past this on start
+	logFileMaxSize = 5 * 1024 * 1024
+)
+
+func openLogFile(dataDir string) (*os.File, error) {
+	path := filepath.Join(dataDir, logFileName)
+	if info, err := os.Stat(path); err == nil && info.Size() > logFileMaxSize {
+		os.Rename(path, path+".1")
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+}
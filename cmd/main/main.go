@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"context"
 	"embed"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -17,13 +19,16 @@ import (
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/appservices"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/datadir"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/helpers"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/tokencrypt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/nicklaw5/helix/v2"
 	"github.com/recws-org/recws"
-	"golang.org/x/net/websocket"
 )
 
 type twitchData struct {
@@ -35,21 +40,95 @@ type twitchData struct {
 }
 
 func main() {
+	dataDirFlag := flag.String("data-dir", "", "folder for the database, logs and output files, defaults to $"+datadir.EnvVar+" or the OS app data folder")
+	flag.Parse()
+	dataDir, err := datadir.Resolve(*dataDirFlag)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = datadir.Ensure(dataDir)
+	if err != nil {
+		log.Fatalln("Failed to create data directory", err)
+	}
+	logFile, err := openLogFile(dataDir)
+	if err != nil {
+		log.Println("Failed to open log file, logging to console only", err)
+	} else {
+		defer logFile.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	}
+	log.Println("Data directory:", dataDir)
+
+	dbPath := databaseconn.Path(dataDir)
+	moved, err := databaseconn.AdoptLegacy(dbPath)
+	if err != nil {
+		log.Fatalln("Failed to move the database from the working directory", err)
+	}
+	if moved {
+		log.Println("Moved the database from the working directory to", dbPath)
+	}
+	err = databaseconn.Create(dbPath)
+	if err != nil {
+		log.Fatalln("Failed to create database", err)
+	}
+
+	if flag.Arg(0) == "migrate" {
+		err := runMigrateCommand(dbPath, flag.Args()[1:])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	helpers.PreflightTest()
-	app := NewApp()
+	err = helpers.PreflightTest(dbPath)
+	if err != nil {
+		log.Println("Failed to apply database upgrades, the app cannot start")
+		// keep a console window opened by double clicking the app up long enough to read why
+		if stdinIsTerminal() {
+			fmt.Print("Press 'Enter' to exit...")
+			bufio.NewReader(os.Stdin).ReadBytes('\n')
+		}
+		os.Exit(1)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		log.Fatalln("Failed to open database", err)
+	}
+	defer st.Close()
+	if flag.Arg(0) == "tokens" {
+		err := runTokensCommand(st, flag.Args()[1:])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	err = st.EnableTokenEncryption(context.Background(), dataDir)
+	if err == store.ErrWrongTokenKey || err == store.ErrTokenPassphraseRequired {
+		log.Fatalln(err.Error() + ". Use the " + tokencrypt.KeyFileName + " or passphrase they were saved with, or run the app with `tokens reset` to delete them and log in to Twitch again")
+	}
+	if err != nil {
+		log.Fatalln("Failed to set up the Twitch token encryption", err)
+	}
+	app := NewApp(st, dataDir)
 
 	go func() {
 		log.Println(app.Run())
 	}()
 	<-sigs
 	app.cancel()
+	app.finishPlay(false)
 
 	fmt.Print("Press 'Enter' to continue...")
 	bufio.NewReader(os.Stdin).ReadBytes('\n')
 }
 
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 type App struct {
 	twitchDataStruct        *twitchData
 	twitchDataStructBot     *twitchData
@@ -62,13 +141,29 @@ type App struct {
 	pearDesktopIncomingMsgs chan []byte
 	ctx                     context.Context
 	cancel                  context.CancelFunc
-	clients                 map[*websocket.Conn]struct{}
+	clients                 map[*wsClient]struct{}
 	clientsMu               sync.RWMutex
-	clientsBroadcast        chan string
 	songRequestRewardID     string
+	permissionRules         map[string]songrequests.PermissionRule
+	followerMinAge          time.Duration
+	sharedChatRequests      songrequests.SharedChatRequestMode
+	creditSettings          map[string]int
+	nowPlayingSettings      map[string]string
+	nowPlayingThisStream    bool
+	fileOutputSettings      map[string]string
+	timingSettings          map[string]int
+	publicQueueSettings     map[string]string
+	requestsClosed          bool
+	requireApproval         bool
+	settingsMu              sync.RWMutex
+	sessionSecret           string
+	launchToken             string
+	launchTokenMu           sync.Mutex
+	store                   *store.Store
+	dataDir                 string
 }
 
-func NewApp() *App {
+func NewApp(st *store.Store, dataDir string) *App {
 	ctx, cancel := context.WithCancel(context.Background())
 	c, _ := helix.NewClient(&helix.Options{
 		ClientID: data.GetTwitchClientID(),
@@ -83,11 +178,19 @@ func NewApp() *App {
 		cancel:                  cancel,
 		helix:                   c,
 		helixBot:                c2,
-		clientsBroadcast:        make(chan string),
 		twitchWSIncomingMsgs:    make(chan []byte),
 		clientsMu:               sync.RWMutex{},
-		clients:                 make(map[*websocket.Conn]struct{}),
+		clients:                 make(map[*wsClient]struct{}),
 		pearDesktopIncomingMsgs: make(chan []byte),
+		permissionRules:         make(map[string]songrequests.PermissionRule),
+		creditSettings:          make(map[string]int),
+		nowPlayingSettings:      make(map[string]string),
+		fileOutputSettings:      make(map[string]string),
+		timingSettings:          make(map[string]int),
+		publicQueueSettings:     make(map[string]string),
+		launchToken:             randomHex(16),
+		store:                   st,
+		dataDir:                 dataDir,
 	}
 }
 
@@ -100,6 +203,11 @@ func (a *App) Run() error {
 	if err != nil {
 		return err
 	}
+	err = a.saveSessionSecret()
+	if err != nil {
+		return err
+	}
+	a.pruneSearchCache()
 
 	// Auto reconnect pear desktop and funnel mesasges to channel
 	log.Println("Pear Desktop WS service starting...")
@@ -183,32 +291,22 @@ func (a *App) Run() error {
 		}
 	}()
 
-	// Send msgs to ws clients
-	go func() {
-		for {
-			data := <-a.clientsBroadcast
-			a.clientsMu.Lock()
-			for ws := range a.clients {
-				websocket.Message.Send(ws, data)
-			}
-			a.clientsMu.Unlock()
-		}
-	}()
-
 	// Process song requests
 	go func() {
 		for msg := range srChan {
-			a.songRequestLogic(msg.song, msg.event)
+			a.songRequestLogic(msg.song, msg.event, msg.usedCredit)
 		}
 	}()
 
 	// Echo instance
 	e := echo.New()
+	e.Logger.SetOutput(log.Writer())
 	e.HideBanner = true
 	e.HidePort = true
 
 	// Middleware
 	e.Use(middleware.Recover())
+	e.Use(a.validateHostOrigin)
 	e.Use(middleware.StaticWithConfig(middleware.StaticConfig{
 		Root:       "build",
 		Index:      "index.html",
@@ -216,10 +314,39 @@ func (a *App) Run() error {
 		HTML5:      true,
 	}))
 
+	e.GET("/session", a.handleSessionLaunch)
+
+	// The websocket and preset reads stay open for OBS overlays, the websocket is read only without a session
 	apiV1 := e.Group("/api/v1")
-	apiV1.POST("/twitch-oauth", a.processTwitchOAuth)
-	apiV1.PATCH("/settings", a.processTwitchSettings)
 	apiV1.GET("/ws", a.handleAppWs)
+	apiV1.GET("/overlay-presets/:name", a.getOverlayPreset)
+	apiV1.POST("/oauth-state", a.createOAuthState, a.requireSession)
+	apiV1.POST("/twitch-oauth", a.processTwitchOAuth, a.requireSession)
+	apiV1.GET("/settings", a.getSettings, a.requireSession)
+	apiV1.PATCH("/settings", a.processTwitchSettings, a.requireSession)
+	apiV1.GET("/requests", a.getRequests, a.requireSession)
+	apiV1.DELETE("/requests/:id", a.deleteRequest, a.requireSession)
+	apiV1.PATCH("/requests/:id", a.patchRequest, a.requireSession)
+	apiV1.GET("/history", a.getHistory, a.requireSession)
+	apiV1.POST("/history/:id/requeue", a.requeueHistory, a.requireSession)
+	apiV1.GET("/plays", a.getPlays, a.requireSession)
+	apiV1.GET("/stats", a.getStats, a.requireSession)
+	apiV1.GET("/stats/users/:id", a.getUserStats, a.requireSession)
+	apiV1.GET("/export", a.getExport, a.requireSession)
+	apiV1.GET("/export/history.csv", a.getHistoryCSV, a.requireSession)
+	apiV1.GET("/export/redacted-db", a.getRedactedDatabase, a.requireSession)
+	apiV1.POST("/import", a.postImport, a.requireSession)
+	apiV1.GET("/search-cache", a.getSearchCache, a.requireSession)
+	apiV1.GET("/blocklist", a.getBlocklist, a.requireSession)
+	apiV1.POST("/blocklist", a.postBlocklist, a.requireSession)
+	apiV1.DELETE("/blocklist", a.deleteBlocklist, a.requireSession)
+	apiV1.DELETE("/search-cache", a.deleteSearchCache, a.requireSession)
+	apiV1.GET("/overlay-presets", a.getOverlayPresets, a.requireSession)
+	apiV1.PUT("/overlay-presets/:name", a.putOverlayPreset, a.requireSession)
+	apiV1.DELETE("/overlay-presets/:name", a.deleteOverlayPreset, a.requireSession)
+
+	e.GET("/overlay/:name", a.handleOverlayPage)
+	e.GET("/overlay/assets/:file", a.handleOverlayAsset)
 
 	var cmd string
 	var args []string
@@ -232,7 +359,7 @@ func (a *App) Run() error {
 	default: // "linux", "freebsd", "openbsd", "netbsd"
 		cmd = "xdg-open"
 	}
-	args = append(args, "http://localhost:3999/") // must use localhost here because twitch does not allow 127.0.0.1
+	args = append(args, a.launchUrl()) // must use localhost here because twitch does not allow 127.0.0.1
 	twitchTokenExpiresSoon := a.twitchDataStruct.isAuthenticated && time.Now().Add(-15*24*time.Hour).After(a.twitchDataStruct.expiresDate)
 	if a.twitchDataStruct.isAuthenticated && twitchTokenExpiresSoon {
 		log.Println("ALERT! Token expiry is soon, consider refreshing token.")
@@ -241,11 +368,14 @@ func (a *App) Run() error {
 	if a.twitchDataStructBot.isAuthenticated && twitchTokenBotExpiresSoon {
 		log.Println("ALERT! Bot Token expiry is soon, consider refreshing token.")
 	}
-	if !a.twitchDataStruct.isAuthenticated || a.songRequestRewardID == "" || twitchTokenExpiresSoon || twitchTokenBotExpiresSoon {
+	// the log file ends up in bug reports, so the sign in link is only printed to the console
+	if !a.twitchDataStruct.isAuthenticated || a.rewardID() == "" || twitchTokenExpiresSoon || twitchTokenBotExpiresSoon {
+		log.Println("Opening the control panel at " + controlPanelUrl)
 		exec.Command(cmd, args...).Start()
 	} else {
 		time.Sleep(5 * time.Second)
-		log.Println("Friendly reminder, the control panel is available at http://localhost:3999/")
+		log.Println("Friendly reminder, the control panel is available at " + controlPanelUrl)
+		fmt.Fprintln(os.Stderr, "To sign in from a new browser, open this link once: "+a.launchUrl())
 	}
 	return e.Start("127.0.0.1:3999")
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+)
+
+const migrateUsage = "usage: migrate status | migrate backup | migrate restore [backup file]"
+
+// runMigrateCommand is `migrate status|backup|restore`, run with the app closed
+func runMigrateCommand(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "status":
+		status, err := databaseconn.Status(dbPath)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Database:", dbPath)
+		fmt.Println("Version:", status.Version, "of", status.Latest)
+		if status.Dirty {
+			fmt.Println("Dirty: an upgrade to this version did not finish, restore a backup")
+		}
+		if len(status.Pending) > 0 {
+			fmt.Println("Pending upgrades:", status.Pending)
+		}
+		backups, err := databaseconn.ListBackups(dbPath)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Backups in", databaseconn.BackupsDir(dbPath)+":")
+		if len(backups) == 0 {
+			fmt.Println("  none")
+		}
+		for _, v := range backups {
+			fmt.Println(" ", v)
+		}
+	case "backup":
+		backupPath, err := databaseconn.Backup(dbPath, databaseconn.BackupReasonManual)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Backed up to", backupPath)
+	case "restore":
+		backupPath := ""
+		if len(args) > 1 {
+			backupPath = args[1]
+		} else {
+			backups, err := databaseconn.ListBackups(dbPath)
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				return errors.New("there are no backups in " + databaseconn.BackupsDir(dbPath))
+			}
+			backupPath = backups[0]
+		}
+		// keep what is being replaced, it may be the only copy of recent history
+		current, err := databaseconn.Backup(dbPath, databaseconn.BackupReasonRestore)
+		if err != nil {
+			return errors.New("cannot back up the current database before restoring: " + err.Error())
+		}
+		fmt.Println("Current database backed up to", current)
+		if err := databaseconn.Restore(dbPath, backupPath); err != nil {
+			return err
+		}
+		fmt.Println("Restored", backupPath)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/utils"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/nicklaw5/helix/v2"
+)
+
+const (
+	nowPlayingModeRequests = "requests"
+	nowPlayingModeAll      = "all"
+
+	nowPlayingMethodMessage      = "message"
+	nowPlayingMethodAnnouncement = "announcement"
+)
+
+// Templates accept {title}, {artist}, {link} and {requester}
+var defaultNowPlayingSettings = map[string]string{
+	data.DB_KEY_NOW_PLAYING_ENABLED:         "false",
+	data.DB_KEY_NOW_PLAYING_MODE:            nowPlayingModeRequests,
+	data.DB_KEY_NOW_PLAYING_METHOD:          nowPlayingMethodMessage,
+	data.DB_KEY_NOW_PLAYING_TEMPLATE:        "Now playing: {title} - {artist} {link} requested by @{requester}",
+	data.DB_KEY_NOW_PLAYING_TEMPLATE_FILLER: "Now playing: {title} - {artist} {link}",
+}
+
+func (a *App) nowPlayingSetting(key string) string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if v, ok := a.nowPlayingSettings[key]; ok {
+		return v
+	}
+	return defaultNowPlayingSettings[key]
+}
+
+func checkNowPlayingSetting(key string, value string) error {
+	if _, ok := defaultNowPlayingSettings[key]; !ok {
+		return errors.New("unknown now playing setting " + key)
+	}
+	switch key {
+	case data.DB_KEY_NOW_PLAYING_ENABLED:
+		if value != "true" && value != "false" {
+			return errors.New(key + " must be true or false")
+		}
+	case data.DB_KEY_NOW_PLAYING_MODE:
+		if value != nowPlayingModeRequests && value != nowPlayingModeAll {
+			return errors.New(key + " must be " + nowPlayingModeRequests + " or " + nowPlayingModeAll)
+		}
+	case data.DB_KEY_NOW_PLAYING_METHOD:
+		if value != nowPlayingMethodMessage && value != nowPlayingMethodAnnouncement {
+			return errors.New(key + " must be " + nowPlayingMethodMessage + " or " + nowPlayingMethodAnnouncement)
+		}
+	default:
+		if strings.TrimSpace(value) == "" {
+			return errors.New(key + " cannot be empty")
+		}
+	}
+	return nil
+}
+
+func (a *App) setNowPlayingSetting(key string, value string) error {
+	if err := checkNowPlayingSetting(key, value); err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	a.nowPlayingSettings[key] = value
+	if key == data.DB_KEY_NOW_PLAYING_ENABLED {
+		a.nowPlayingThisStream = value == "true"
+	}
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) nowPlayingSettingsMap() map[string]string {
+	settings := map[string]string{}
+	for k := range defaultNowPlayingSettings {
+		settings[k] = a.nowPlayingSetting(k)
+	}
+	return settings
+}
+
+// setNowPlayingThisStream is the per stream toggle, it goes back to the saved setting when the next stream starts
+func (a *App) setNowPlayingThisStream(enabled bool) {
+	a.settingsMu.Lock()
+	a.nowPlayingThisStream = enabled
+	a.settingsMu.Unlock()
+}
+
+// toggleNowPlayingThisStream handles "!announce on" and "!announce off"
+func (a *App) toggleNowPlayingThisStream(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage) {
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(event.Message.Text, "!announce")))
+	msg := ""
+	switch arg {
+	case "on":
+		a.setNowPlayingThisStream(true)
+		msg = "Now playing announcements are on for this stream."
+	case "off":
+		a.setNowPlayingThisStream(false)
+		msg = "Now playing announcements are off for this stream."
+	default:
+		msg = "Usage: !announce on|off"
+	}
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              msg,
+		ReplyParentMessageID: event.MessageId,
+	})
+}
+
+func (a *App) announceNowPlaying(song playerSonginfo, requestedBy string) {
+	a.settingsMu.RLock()
+	enabled := a.nowPlayingThisStream
+	a.settingsMu.RUnlock()
+	if !enabled || !a.streamOnline || !a.twitchDataStruct.isAuthenticated {
+		return
+	}
+	template := a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_TEMPLATE)
+	if requestedBy == "" {
+		if a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_MODE) != nowPlayingModeAll {
+			return
+		}
+		template = a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_TEMPLATE_FILLER)
+	}
+	msg := utils.ReplaceVars(template, map[string]string{
+		"title":     song.AlternativeTitle,
+		"artist":    song.Artist,
+		"link":      song.GetUrl(),
+		"requester": requestedBy,
+	})
+
+	useProperHelix, properUserID := a.properHelix()
+	if a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_METHOD) == nowPlayingMethodAnnouncement {
+		// sender needs moderator:manage:announcements and to be a mod in the channel
+		resp, err := useProperHelix.SendChatAnnouncement(&helix.SendChatAnnouncementParams{
+			BroadcasterID: a.twitchDataStruct.userID,
+			ModeratorID:   properUserID,
+			Message:       msg,
+		})
+		if err == nil && resp.ErrorMessage == "" {
+			return
+		}
+		if err == nil {
+			err = errors.New(resp.ErrorMessage)
+		}
+		log.Println("Failed to send now playing announcement, falling back to chat message", err)
+	}
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID: a.twitchDataStruct.userID,
+		SenderID:      properUserID,
+		Message:       msg,
+	})
+}
@@ -0,0 +1,110 @@
+// Shared by every overlay page, the theme comes from ?preset=name merged with query params
+// Query params: layout, accent, bg, text, font, size, radius, progress, artwork, requester, max, duration
+
+const overlayDefaults = {
+	layout: "horizontal",
+	progress: "1",
+	artwork: "1",
+	requester: "1",
+	max: "5",
+	duration: "6",
+};
+
+async function loadOverlayTheme() {
+	const params = new URLSearchParams(window.location.search);
+	let theme = { ...overlayDefaults };
+	const preset = params.get("preset");
+	if (preset) {
+		try {
+			const resp = await fetch("/api/v1/overlay-presets/" + encodeURIComponent(preset));
+			if (resp.ok) {
+				theme = { ...theme, ...(await resp.json()) };
+			}
+		} catch (e) {
+			console.log("failed to load overlay preset", e);
+		}
+	}
+	for (const [k, v] of params) {
+		if (k !== "preset") {
+			theme[k] = v;
+		}
+	}
+
+	const root = document.documentElement.style;
+	for (const k of ["accent", "bg", "text", "font"]) {
+		if (theme[k]) {
+			root.setProperty("--" + k, theme[k]);
+		}
+	}
+	if (theme.size) {
+		root.setProperty("--size", parseInt(theme.size, 10) + "px");
+	}
+	if (theme.radius) {
+		root.setProperty("--radius", parseInt(theme.radius, 10) + "px");
+	}
+	document.body.classList.add("layout-" + theme.layout);
+	theme.progress = theme.progress !== "0";
+	theme.artwork = theme.artwork !== "0";
+	theme.requester = theme.requester !== "0";
+	theme.max = parseInt(theme.max, 10) || 5;
+	theme.duration = parseInt(theme.duration, 10) || 6;
+	return theme;
+}
+
+// connectOverlayWs reconnects forever, OBS keeps browser sources open for the whole stream
+function connectOverlayWs(onEvent) {
+	const proto = window.location.protocol === "https:" ? "wss://" : "ws://";
+	const ws = new WebSocket(proto + window.location.host + "/api/v1/ws");
+	ws.onmessage = (msg) => {
+		let d;
+		try {
+			d = JSON.parse(msg.data);
+		} catch {
+			return;
+		}
+		if (d.v !== 1) {
+			return;
+		}
+		onEvent(d);
+	};
+	ws.onclose = () => {
+		setTimeout(() => connectOverlayWs(onEvent), 3000);
+	};
+}
+
+function songCard(theme, song, requestedBy, position) {
+	const card = document.createElement("div");
+	card.className = "card";
+	if (position !== undefined) {
+		const p = document.createElement("div");
+		p.className = "position";
+		p.textContent = position;
+		card.appendChild(p);
+	}
+	if (theme.artwork && song.image_url) {
+		const img = document.createElement("img");
+		img.className = "artwork";
+		img.src = song.image_url;
+		card.appendChild(img);
+	}
+	const info = document.createElement("div");
+	info.className = "info";
+	const title = document.createElement("div");
+	title.className = "title";
+	title.textContent = song.title;
+	const artist = document.createElement("div");
+	artist.className = "artist";
+	artist.textContent = song.artist;
+	info.append(title, artist);
+	if (theme.requester && requestedBy) {
+		const requester = document.createElement("div");
+		requester.className = "requester";
+		requester.append("requested by ");
+		const b = document.createElement("b");
+		b.textContent = requestedBy;
+		requester.appendChild(b);
+		info.appendChild(requester);
+	}
+	card.appendChild(info);
+	return card;
+}
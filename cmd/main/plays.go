@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+)
+
+// a play that stops further than this from the end without !skip was skipped in Pear Desktop itself
+const playSkippedBeforeEndSeconds = 10
+
+// currentPlay is the track Pear Desktop is on, its plays row is saved when it starts
+// and finished when the next one starts
+var currentPlay = struct {
+	id        int32
+	videoID   string
+	duration  int
+	position  int
+	skipped   bool
+	skippedBy string
+}{}
+var currentPlayMutex = sync.Mutex{}
+
+// startPlay finishes the previous play first, isRequest links the play to the request it came from
+func (a *App) startPlay(song playerSonginfo, isRequest bool) {
+	a.finishPlay(true)
+	if song.VideoId == "" {
+		return
+	}
+
+	var requestID *int32
+	if isRequest {
+		id, err := a.store.Requests.SetOutcome(a.ctx, song.VideoId, []songrequests.RequestOutcome{songrequests.RequestOutcomeQueued}, songrequests.RequestOutcomePlayed, "")
+		if err != nil {
+			log.Println("Failed to update request history for", song.VideoId, err)
+		} else if id != 0 {
+			requestID = &id
+		}
+	}
+	id, err := a.store.Plays.Start(a.ctx, model.Plays{
+		VideoID:         song.VideoId,
+		SongTitle:       song.AlternativeTitle,
+		ArtistName:      song.Artist,
+		ImageURL:        song.ImageSrc,
+		DurationSeconds: int32(song.SongDuration),
+		StartedAt:       store.FormatTime(time.Now()),
+		RequestID:       requestID,
+	})
+	if err != nil {
+		log.Println("Failed to save play history for", song.VideoId, err)
+	}
+
+	currentPlayMutex.Lock()
+	currentPlay.id = id
+	currentPlay.videoID = song.VideoId
+	currentPlay.duration = song.SongDuration
+	currentPlay.position = 0
+	currentPlay.skipped = false
+	currentPlay.skippedBy = ""
+	currentPlayMutex.Unlock()
+}
+
+// finishPlay with nextStarted false is for exit, stopping early then does not count as a skip
+func (a *App) finishPlay(nextStarted bool) {
+	currentPlayMutex.Lock()
+	play := currentPlay
+	currentPlay.id = 0
+	currentPlay.videoID = ""
+	currentPlayMutex.Unlock()
+	if play.id == 0 {
+		return
+	}
+	skipped := play.skipped || (nextStarted && play.duration > 0 && play.position < play.duration-playSkippedBeforeEndSeconds)
+	// also runs on exit after a.ctx is cancelled
+	err := a.store.Plays.Finish(context.Background(), play.id, play.position, skipped, play.skippedBy)
+	if err != nil {
+		log.Println("Failed to finish play history for", play.videoID, err)
+	}
+}
+
+func currentPlayVideoID() string {
+	currentPlayMutex.Lock()
+	defer currentPlayMutex.Unlock()
+	return currentPlay.videoID
+}
+
+func setCurrentPlayPosition(position int) {
+	currentPlayMutex.Lock()
+	currentPlay.position = position
+	currentPlayMutex.Unlock()
+}
+
+func markCurrentPlaySkipped(videoID string, by string) {
+	currentPlayMutex.Lock()
+	if currentPlay.videoID == videoID {
+		currentPlay.skipped = true
+		currentPlay.skippedBy = by
+	}
+	currentPlayMutex.Unlock()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// The public queue page runs on its own listener so viewers never reach the control panel routes
+//
+//go:embed public/*
+var publicQueueFS embed.FS
+
+var defaultPublicQueueSettings = map[string]string{
+	data.DB_KEY_PUBLIC_QUEUE_ENABLED:    "false",
+	data.DB_KEY_PUBLIC_QUEUE_ADDRESS:    "127.0.0.1:4000",
+	data.DB_KEY_PUBLIC_QUEUE_BASE_URL:   "",
+	data.DB_KEY_PUBLIC_QUEUE_RATE_LIMIT: "30",
+}
+
+func (a *App) publicQueueSetting(key string) string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if v, ok := a.publicQueueSettings[key]; ok {
+		return v
+	}
+	return defaultPublicQueueSettings[key]
+}
+
+func checkPublicQueueSetting(key string, value string) error {
+	if _, ok := defaultPublicQueueSettings[key]; !ok {
+		return errors.New("unknown public queue setting " + key)
+	}
+	switch key {
+	case data.DB_KEY_PUBLIC_QUEUE_ENABLED:
+		if value != "true" && value != "false" {
+			return errors.New(key + " must be true or false")
+		}
+	case data.DB_KEY_PUBLIC_QUEUE_ADDRESS:
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return errors.New(key + " must be a host and port like 127.0.0.1:4000")
+		}
+	case data.DB_KEY_PUBLIC_QUEUE_BASE_URL:
+		if value != "" {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return errors.New(key + " must be an http or https URL")
+			}
+		}
+	case data.DB_KEY_PUBLIC_QUEUE_RATE_LIMIT:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 600 {
+			return errors.New(key + " must be a number from 1 to 600")
+		}
+	}
+	return nil
+}
+
+func (a *App) setPublicQueueSetting(key string, value string) error {
+	if err := checkPublicQueueSetting(key, value); err != nil {
+		return err
+	}
+	a.settingsMu.Lock()
+	a.publicQueueSettings[key] = value
+	a.settingsMu.Unlock()
+	return nil
+}
+
+// publicQueueListenerChanged reports whether any of the changed keys needs the listener restarted,
+// callers restart once after applying all of them
+func publicQueueListenerChanged(keys []string) bool {
+	for _, k := range keys {
+		if _, ok := defaultPublicQueueSettings[k]; ok && k != data.DB_KEY_PUBLIC_QUEUE_BASE_URL {
+			return true
+		}
+	}
+	return false
+}
+
+// publicQueueUrl is linked by !queue, empty when the page is off or has no base URL
+func (a *App) publicQueueUrl() string {
+	if a.publicQueueSetting(data.DB_KEY_PUBLIC_QUEUE_ENABLED) != "true" {
+		return ""
+	}
+	return a.publicQueueSetting(data.DB_KEY_PUBLIC_QUEUE_BASE_URL)
+}
+
+var publicQueueServerMutex = sync.Mutex{}
+var publicQueueServer *echo.Echo
+
+// restartPublicQueueServer stops the listener and starts it again with the current settings if enabled
+func (a *App) restartPublicQueueServer() {
+	publicQueueServerMutex.Lock()
+	defer publicQueueServerMutex.Unlock()
+
+	if publicQueueServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		publicQueueServer.Shutdown(ctx)
+		cancel()
+		publicQueueServer = nil
+		log.Println("Public queue page stopped")
+	}
+	if a.publicQueueSetting(data.DB_KEY_PUBLIC_QUEUE_ENABLED) != "true" {
+		return
+	}
+
+	perMinute, _ := strconv.Atoi(a.publicQueueSetting(data.DB_KEY_PUBLIC_QUEUE_RATE_LIMIT))
+	e := echo.New()
+	e.Logger.SetOutput(log.Writer())
+	e.HideBanner = true
+	e.HidePort = true
+	// rate limit by the connecting address, X-Forwarded-For and X-Real-IP are up to the viewer
+	e.IPExtractor = echo.ExtractIPDirect()
+	e.Use(middleware.Recover())
+	e.Use(middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(float64(perMinute) / 60),
+			Burst:     perMinute,
+			ExpiresIn: time.Minute * 3,
+		}),
+	}))
+	e.GET("/", echo.StaticFileHandler("public/queue.html", publicQueueFS))
+	e.GET("/queue.json", a.getPublicQueue)
+
+	// bind before returning so the next restart always shuts down a listening server
+	address := a.publicQueueSetting(data.DB_KEY_PUBLIC_QUEUE_ADDRESS)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Println("Public queue page failed", err)
+		return
+	}
+	e.Listener = listener
+	publicQueueServer = e
+	go func() {
+		log.Println("Public queue page listening on", address)
+		err := e.Start(address)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Public queue page failed", err)
+		}
+	}()
+}
+
+var publicQueueCacheMutex = sync.Mutex{}
+var publicQueueCache []byte
+var publicQueueCacheExpiry time.Time
+
+// getPublicQueue only has what chat can already see, cached so viewers cannot hammer Pear Desktop
+func (a *App) getPublicQueue(c echo.Context) error {
+	publicQueueCacheMutex.Lock()
+	defer publicQueueCacheMutex.Unlock()
+	if time.Now().Before(publicQueueCacheExpiry) {
+		return c.JSONBlob(http.StatusOK, publicQueueCache)
+	}
+
+	items, err := a.getRequestItems()
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, echo.Map{
+			"error": "queue is not available right now",
+		})
+	}
+	type publicSong struct {
+		Position    int    `json:"position,omitempty"`
+		Title       string `json:"title"`
+		Artist      string `json:"artist"`
+		ImageUrl    string `json:"image_url"`
+		Url         string `json:"url"`
+		RequestedBy string `json:"requested_by,omitempty"`
+	}
+	var nowPlaying *publicSong
+	queue := []publicSong{}
+	for _, v := range items {
+		song := publicSong{
+			Title:       v.Title,
+			Artist:      v.Artist,
+			ImageUrl:    v.ImageUrl,
+			Url:         v.Url,
+			RequestedBy: v.RequestedBy,
+		}
+		if v.Position == 0 {
+			songQueueMutex.RLock()
+			song.RequestedBy = playerInfo.RequestedBy
+			songQueueMutex.RUnlock()
+			nowPlaying = &song
+			continue
+		}
+		if v.RequestedBy == "" {
+			continue
+		}
+		song.Position = len(queue) + 1
+		queue = append(queue, song)
+	}
+	b, _ := json.Marshal(echo.Map{
+		"now_playing": nowPlaying,
+		"queue":       queue,
+	})
+	publicQueueCache = b
+	publicQueueCacheExpiry = time.Now().Add(time.Second * 5)
+	return c.JSONBlob(http.StatusOK, b)
+}
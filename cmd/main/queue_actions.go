@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/labstack/echo/v4"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Queue actions are shared by chat commands, the REST API and control panel websocket commands
+
+var errRequestNotFound = errors.New("request not found in upcoming songs")
+var errPendingNotFound = errors.New("pending request not found")
+var errSkipCooldown = errors.New("skip is on cooldown")
+
+// skipSong returns errSkipCooldown while the skip cooldown is running, by is the login kept in play history.
+// The song is only marked skipped once Pear Desktop took the skip.
+func (a *App) skipSong(by string) (string, error) {
+	cooldown := time.Duration(a.timingSetting(data.DB_KEY_SKIP_COOLDOWN_SECONDS)) * time.Second
+	skipMutex.Lock()
+	if !time.Now().After(lastSkipped.Add(cooldown)) {
+		skipMutex.Unlock()
+		return "", errSkipCooldown
+	}
+	songQueueMutex.Lock()
+	err := songrequests.SkipPearSong()
+	if err != nil {
+		songQueueMutex.Unlock()
+		skipMutex.Unlock()
+		return "", err
+	}
+	if playerInfo.RequestedBy != "" {
+		go a.setRequestOutcome(playerInfo.Song.VideoId, songrequests.RequestOutcomePlayed, songrequests.RequestOutcomeSkipped)
+	}
+	markCurrentPlaySkipped(playerInfo.Song.VideoId, by)
+	songQueueMutex.Unlock()
+	lastSkipped = time.Now()
+	skipMutex.Unlock()
+
+	s := "Skipped song!"
+	if songQueueMutex.TryRLock() {
+		s = "Skipped " + playerInfo.Song.AlternativeTitle + "!"
+		songQueueMutex.RUnlock()
+	}
+	return s, nil
+}
+
+func (a *App) removeRequest(videoID string) error {
+	songQueueMutex.Lock()
+	defer songQueueMutex.Unlock()
+	queue, err := songrequests.GetPearQueue()
+	if err != nil {
+		return err
+	}
+	index := queue.IndexAfterSelected(videoID)
+	if index == -1 {
+		return errRequestNotFound
+	}
+	err = songrequests.RemoveFromPearQueue(index)
+	if err != nil {
+		return err
+	}
+	for i, v := range songQueue {
+		if v.song.VideoID == videoID {
+			songQueue = append(songQueue[:i], songQueue[i+1:]...)
+			go a.setRequestOutcome(videoID, songrequests.RequestOutcomeQueued, songrequests.RequestOutcomeRemoved)
+			break
+		}
+	}
+	go a.broadcastRequests()
+	return nil
+}
+
+// moveRequest puts a song at position, 1 is the song after the one playing now
+func (a *App) moveRequest(videoID string, position int) error {
+	if position < 1 {
+		return errors.New("position must be 1 or more")
+	}
+	songQueueMutex.Lock()
+	defer songQueueMutex.Unlock()
+	queue, err := songrequests.GetPearQueue()
+	if err != nil {
+		return err
+	}
+	index := queue.IndexAfterSelected(videoID)
+	if index == -1 {
+		return errRequestNotFound
+	}
+	toIndex := queue.SelectedIndex() + position
+	if toIndex >= len(queue.Items) {
+		toIndex = len(queue.Items) - 1
+	}
+	if toIndex == index {
+		return nil
+	}
+	err = songrequests.MoveInPearQueue(index, toIndex)
+	if err != nil {
+		return err
+	}
+	queue, err = songrequests.GetPearQueue()
+	if err == nil {
+		reorderSongQueue(queue)
+	}
+	go a.broadcastRequests()
+	return nil
+}
+
+func (a *App) banRequester(userID string, userLogin string) error {
+	if userID == "" {
+		return errors.New("user id is required")
+	}
+	err := a.store.Users.Ban(a.ctx, userID, userLogin)
+	if err != nil {
+		return err
+	}
+	log.Println("Banned", userLogin, "from song requests")
+	return nil
+}
+
+func (a *App) isRequestBanned(userID string) bool {
+	banned, err := a.store.Users.IsBanned(a.ctx, userID)
+	if err != nil {
+		log.Println("Failed to check request bans", err)
+		return false
+	}
+	return banned
+}
+
+func (a *App) setRequestsOpen(open bool) {
+	a.settingsMu.Lock()
+	a.requestsClosed = !open
+	a.settingsMu.Unlock()
+	go a.broadcastRequestsState()
+}
+
+func (a *App) requestsOpen() bool {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return !a.requestsClosed
+}
+
+func (a *App) setRequireApproval(value string) error {
+	if value != "true" && value != "false" {
+		return errors.New("requests require approval must be true or false")
+	}
+	a.settingsMu.Lock()
+	a.requireApproval = value == "true"
+	a.settingsMu.Unlock()
+	go a.broadcastRequestsState()
+	return nil
+}
+
+func (a *App) requestsRequireApproval() bool {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.requireApproval
+}
+
+type pendingRequest struct {
+	song       *songrequests.SongResult
+	event      twitch.EventChannelChatMessage
+	usedCredit bool
+	receivedAt time.Time
+}
+
+var pendingRequestsMutex = sync.Mutex{}
+
+// Waiting for approval from the control panel, keyed by the chat message ID of the request
+var pendingRequests = []pendingRequest{}
+
+func (a *App) addPendingRequest(song *songrequests.SongResult, event twitch.EventChannelChatMessage, usedCredit bool) {
+	pendingRequestsMutex.Lock()
+	pendingRequests = append(pendingRequests, pendingRequest{
+		song:       song,
+		event:      event,
+		usedCredit: usedCredit,
+		receivedAt: time.Now(),
+	})
+	pendingRequestsMutex.Unlock()
+	go a.broadcastRequestsState()
+}
+
+func (a *App) takePendingRequest(id string) (pendingRequest, error) {
+	pendingRequestsMutex.Lock()
+	defer pendingRequestsMutex.Unlock()
+	for i, v := range pendingRequests {
+		if v.event.MessageId == id {
+			pendingRequests = append(pendingRequests[:i], pendingRequests[i+1:]...)
+			return v, nil
+		}
+	}
+	return pendingRequest{}, errPendingNotFound
+}
+
+func (a *App) approvePendingRequest(id string) error {
+	p, err := a.takePendingRequest(id)
+	if err != nil {
+		return err
+	}
+	useProperHelix, properUserID := a.properHelix()
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        p.event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              "Approved song: " + p.song.Title + " - " + p.song.Artist + " " + "https://youtu.be/" + p.song.VideoID,
+		ReplyParentMessageID: p.event.MessageId,
+	})
+	go func() {
+		srChan <- struct {
+			song       *songrequests.SongResult
+			event      twitch.EventChannelChatMessage
+			usedCredit bool
+		}{
+			song:       p.song,
+			event:      p.event,
+			usedCredit: p.usedCredit,
+		}
+	}()
+	go a.broadcastRequestsState()
+	return nil
+}
+
+func (a *App) rejectPendingRequest(id string) error {
+	p, err := a.takePendingRequest(id)
+	if err != nil {
+		return err
+	}
+	if p.usedCredit {
+		a.refundRequestCredit(p.event.ChatterUserId)
+	}
+	go a.requestRejected(p.event, p.song, requestRejectedNotApproved)
+	useProperHelix, properUserID := a.properHelix()
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        p.event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              "Your request for " + p.song.Title + " - " + p.song.Artist + " was not approved.",
+		ReplyParentMessageID: p.event.MessageId,
+	})
+	go a.broadcastRequestsState()
+	return nil
+}
+
+func (a *App) requestsState() string {
+	pending := []echo.Map{}
+	pendingRequestsMutex.Lock()
+	for _, v := range pendingRequests {
+		pending = append(pending, echo.Map{
+			"id":              v.event.MessageId,
+			"video_id":        v.song.VideoID,
+			"title":           v.song.Title,
+			"artist":          v.song.Artist,
+			"image_url":       v.song.ImageUrl,
+			"requested_by":    v.event.ChatterUserLogin,
+			"requested_by_id": v.event.ChatterUserId,
+			"requested_at":    v.receivedAt,
+		})
+	}
+	pendingRequestsMutex.Unlock()
+	return appWsEvent("REQUESTS_STATE", echo.Map{
+		"open":             a.requestsOpen(),
+		"require_approval": a.requestsRequireApproval(),
+		"pending":          pending,
+	})
+}
+
+func (a *App) broadcastRequestsState() {
+	a.broadcastAuthenticated(a.requestsState())
+}
+
+// properHelix picks the bot account to talk in chat when it is connected
+func (a *App) properHelix() (*helix.Client, string) {
+	if a.twitchDataStructBot.isAuthenticated {
+		return a.helixBot, a.twitchDataStructBot.userID
+	}
+	return a.helix, a.twitchDataStruct.userID
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// 0 disables earning credits from that event
+var defaultCreditSettings = map[string]int{
+	data.DB_KEY_CREDITS_CHEER_BITS:     0,
+	data.DB_KEY_CREDITS_PER_SUB:        0,
+	data.DB_KEY_CREDITS_PER_GIFTED_SUB: 0,
+	data.DB_KEY_CREDITS_PER_RESUB:      0,
+	data.DB_KEY_CREDITS_PER_RAID:       0,
+}
+
+func (a *App) creditSetting(key string) int {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if v, ok := a.creditSettings[key]; ok {
+		return v
+	}
+	return defaultCreditSettings[key]
+}
+
+func (a *App) setCreditSetting(key string, value string) error {
+	if _, ok := defaultCreditSettings[key]; !ok {
+		return errors.New("unknown credit setting " + key)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New(key + " must be a positive number")
+	}
+	a.settingsMu.Lock()
+	a.creditSettings[key] = n
+	a.settingsMu.Unlock()
+	return nil
+}
+
+func (a *App) creditSettingsMap() map[string]string {
+	settings := map[string]string{}
+	for k := range defaultCreditSettings {
+		settings[k] = strconv.Itoa(a.creditSetting(k))
+	}
+	return settings
+}
+
+func (a *App) grantRequestCredits(userID string, userLogin string, credits int, reason string) {
+	if credits <= 0 || userID == "" {
+		return
+	}
+	err := a.store.Users.GrantCredits(a.ctx, userID, userLogin, credits)
+	if err != nil {
+		log.Println("Failed to grant request credits to", userLogin, err)
+		return
+	}
+	log.Println(userLogin, "earned", credits, "song request credits for", reason)
+
+	useProperHelix, properUserID := a.properHelix()
+	s := "credits"
+	if credits == 1 {
+		s = "credit"
+	}
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID: a.twitchDataStruct.userID,
+		SenderID:      properUserID,
+		Message:       "@" + userLogin + " earned " + strconv.Itoa(credits) + " song request " + s + " for the " + reason + "! Use !sr to request a song.",
+	})
+}
+
+func (a *App) getRequestCredits(userID string) (int, error) {
+	return a.store.Users.Credits(a.ctx, userID)
+}
+
+// consumeRequestCredit takes one credit, it reports false when there was none left to take
+func (a *App) consumeRequestCredit(userID string) (bool, error) {
+	return a.store.Users.ConsumeCredit(a.ctx, userID)
+}
+
+// refundRequestCredit gives back a consumed credit when the song could not be queued after all
+func (a *App) refundRequestCredit(userID string) {
+	err := a.store.Users.RefundCredit(a.ctx, userID)
+	if err != nil {
+		log.Println("Failed to refund request credit", err)
+	}
+}
+
+// hasRequestCredits lets chatters without the role for !sr request with credits instead
+func (a *App) hasRequestCredits(userID string) bool {
+	credits, err := a.getRequestCredits(userID)
+	if err != nil {
+		log.Println("Failed to get request credits", err)
+		return false
+	}
+	return credits > 0
+}
+
+func (a *App) replyRequestCredits(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage) {
+	credits, err := a.getRequestCredits(event.ChatterUserId)
+	msg := "You have " + strconv.Itoa(credits) + " song request credits."
+	if credits == 1 {
+		msg = "You have 1 song request credit."
+	}
+	if err != nil {
+		msg = "Internal error when getting your song request credits"
+		log.Println(msg, err)
+	}
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              msg,
+		ReplyParentMessageID: event.MessageId,
+	})
+}
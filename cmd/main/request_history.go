@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+func requestSource(event twitch.EventChannelChatMessage) songrequests.RequestSource {
+	if event.MessageId == "" {
+		// queued from the control panel, there is no chat message behind it
+		return songrequests.RequestSourceAPI
+	}
+	if event.ChannelPointsCustomRewardId != "" {
+		return songrequests.RequestSourceReward
+	}
+	return songrequests.RequestSourceChat
+}
+
+func (a *App) saveRequestHistory(song *songrequests.SongResult, event twitch.EventChannelChatMessage, outcome songrequests.RequestOutcome, reason string) {
+	srData := model.SongRequests{
+		VideoID:    song.VideoID,
+		SongTitle:  song.Title,
+		ArtistName: song.Artist,
+		ImageURL:   song.ImageUrl,
+	}
+	now := store.FormatTime(time.Now())
+	srrData := model.SongRequestRequesters{
+		VideoID:        song.VideoID,
+		TwitchUserID:   event.ChatterUserId,
+		TwitchUsername: event.ChatterUserLogin,
+		RequestedAt:    now,
+		Source:         requestSource(event),
+		Outcome:        outcome,
+		OutcomeReason:  reason,
+		OutcomeAt:      now,
+	}
+	err := a.store.Requests.Add(a.ctx, srData, srrData)
+	if err != nil {
+		log.Println("Somehow failed to save !sr history to database", err)
+	}
+}
+
+// requestRejected tells the control panel and keeps the rejection in history when the song was found
+func (a *App) requestRejected(event twitch.EventChannelChatMessage, song *songrequests.SongResult, reason string) {
+	if song != nil {
+		a.saveRequestHistory(song, event, songrequests.RequestOutcomeRejected, reason)
+	}
+	a.broadcastRequestRejected(event, song, reason)
+}
+
+func (a *App) setRequestOutcome(videoID string, from songrequests.RequestOutcome, outcome songrequests.RequestOutcome) {
+	_, err := a.store.Requests.SetOutcome(a.ctx, videoID, []songrequests.RequestOutcome{from}, outcome, "")
+	if err != nil {
+		log.Println("Failed to update request history for", videoID, err)
+	}
+}
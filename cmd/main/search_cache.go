@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	"github.com/labstack/echo/v4"
+)
+
+// searchCacheStaleFor is how long past its TTL an entry still stands in when Pear's search fails
+const searchCacheStaleFor = 30 * 24 * time.Hour
+
+func (a *App) searchCacheTTL() time.Duration {
+	return time.Duration(a.timingSetting(data.DB_KEY_SEARCH_CACHE_TTL_HOURS)) * time.Hour
+}
+
+// searchSong resolves a !sr query through the search cache, falling back to Pear's search.
+// The length limits are checked on every request since they can change after a song was cached
+func (a *App) searchSong(query string, minDuration int, maxDuration int) (*songrequests.SongResult, error) {
+	ctx := context.Background()
+	ttl := a.searchCacheTTL()
+	var stale *songrequests.SongResult
+	if ttl > 0 {
+		cached, cachedAt, err := a.store.Search.Get(ctx, query)
+		if err == nil {
+			age := time.Since(cachedAt)
+			if age < ttl {
+				return cached, songrequests.CheckDuration(cached, minDuration, maxDuration)
+			}
+			if age < ttl+searchCacheStaleFor {
+				stale = cached
+			}
+		}
+	}
+
+	song, err := songrequests.FindSong(query)
+	if err != nil {
+		// no results is an answer, anything else means the search itself is not working
+		if stale != nil && !errors.Is(err, songrequests.ErrNoResults) {
+			log.Println("Search failed, using the cached result for", query, err)
+			return stale, songrequests.CheckDuration(stale, minDuration, maxDuration)
+		}
+		return nil, err
+	}
+	if ttl > 0 {
+		if err := a.store.Search.Put(ctx, query, song); err != nil {
+			log.Println("Failed to cache search result", err)
+		}
+	}
+	return song, songrequests.CheckDuration(song, minDuration, maxDuration)
+}
+
+// pruneSearchCache drops entries too old to be used even as a fallback
+func (a *App) pruneSearchCache() {
+	n, err := a.store.Search.Prune(context.Background(), time.Now().Add(-a.searchCacheTTL()-searchCacheStaleFor))
+	if err != nil {
+		log.Println("Failed to prune the search cache", err)
+		return
+	}
+	if n > 0 {
+		log.Println("Removed", n, "old search cache entries")
+	}
+}
+
+func (a *App) getSearchCache(c echo.Context) error {
+	count, err := a.store.Search.Count(c.Request().Context())
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot read the search cache",
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"entries":   count,
+		"ttl_hours": a.timingSetting(data.DB_KEY_SEARCH_CACHE_TTL_HOURS),
+	})
+}
+
+// deleteSearchCache forgets one query or videoId with ?q=, everything without it
+func (a *App) deleteSearchCache(c echo.Context) error {
+	var removed int64
+	var err error
+	if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+		removed, err = a.store.Search.Invalidate(c.Request().Context(), songrequests.ParseSearchQuery(q))
+	} else {
+		removed, err = a.store.Search.Clear(c.Request().Context())
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{
+			"error": "cannot clear the search cache",
+		})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"removed": removed,
+	})
+}
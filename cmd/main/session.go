@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/labstack/echo/v4"
+)
+
+const sessionCookieName = "session"
+
+// Anything else in Host is DNS rebinding, anything else in Origin is another website
+var allowedHosts = map[string]struct{}{
+	"localhost:3999": {},
+	"127.0.0.1:3999": {},
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// saveSessionSecret creates the per install secret the first time the app runs
+func (a *App) saveSessionSecret() error {
+	if a.sessionSecret != "" {
+		return nil
+	}
+	secret := randomHex(32)
+	err := a.store.Settings.Set(a.ctx, data.DB_KEY_SESSION_SECRET, secret)
+	if err != nil {
+		return errors.New("Failed to save session secret, original error:\n" + err.Error())
+	}
+	a.sessionSecret = secret
+	return nil
+}
+
+const controlPanelUrl = "http://localhost:3999"
+
+// launchUrl is opened in the browser on start, the token in it works once and only until the app exits
+func (a *App) launchUrl() string {
+	a.launchTokenMu.Lock()
+	defer a.launchTokenMu.Unlock()
+	return controlPanelUrl + "/session?token=" + a.launchToken
+}
+
+// takeLaunchToken reports whether token is the launch token and uses it up
+func (a *App) takeLaunchToken(token string) bool {
+	a.launchTokenMu.Lock()
+	defer a.launchTokenMu.Unlock()
+	if token == "" || a.launchToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.launchToken)) != 1 {
+		return false
+	}
+	a.launchToken = ""
+	return true
+}
+
+func (a *App) hasSession(c echo.Context) bool {
+	cookie, err := c.Cookie(sessionCookieName)
+	if err != nil || a.sessionSecret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(a.sessionSecret)) == 1
+}
+
+// handleSessionLaunch trades the launch token for the session cookie
+func (a *App) handleSessionLaunch(c echo.Context) error {
+	if !a.takeLaunchToken(c.QueryParam("token")) {
+		return c.String(http.StatusUnauthorized, "Invalid or already used link, restart the app and open the control panel from the new link in the app console.")
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookieName,
+		Value:    a.sessionSecret,
+		Path:     "/",
+		MaxAge:   int((time.Hour * 24 * 365).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return c.Redirect(http.StatusFound, "/")
+}
+
+// validateHostOrigin applies to every route, GET without an Origin is fine so OBS and links keep working
+func (a *App) validateHostOrigin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := allowedHosts[c.Request().Host]; !ok {
+			return c.NoContent(http.StatusForbidden)
+		}
+		origin := c.Request().Header.Get(echo.HeaderOrigin)
+		if origin == "" {
+			if c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead {
+				return next(c)
+			}
+			return c.NoContent(http.StatusForbidden)
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme != "http" {
+			return c.NoContent(http.StatusForbidden)
+		}
+		if _, ok := allowedHosts[u.Host]; !ok {
+			return c.NoContent(http.StatusForbidden)
+		}
+		return next(c)
+	}
+}
+
+func (a *App) requireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !a.hasSession(c) {
+			return c.JSON(http.StatusUnauthorized, echo.Map{
+				"error": "not signed in, open the control panel from the link in the app console",
+			})
+		}
+		return next(c)
+	}
+}
+
+type oauthState struct {
+	forBot     bool
+	timeExpiry time.Time
+}
+
+var oauthStatesMutex = sync.Mutex{}
+
+// Nonces handed to the control panel for the Twitch OAuth state param, single use
+var oauthStates = map[string]oauthState{}
+
+func newOAuthState(forBot bool) string {
+	oauthStatesMutex.Lock()
+	defer oauthStatesMutex.Unlock()
+	for k, v := range oauthStates {
+		if time.Now().After(v.timeExpiry) {
+			delete(oauthStates, k)
+		}
+	}
+	state := randomHex(16)
+	oauthStates[state] = oauthState{
+		forBot:     forBot,
+		timeExpiry: time.Now().Add(time.Minute * 10),
+	}
+	return state
+}
+
+func takeOAuthState(state string) (oauthState, bool) {
+	oauthStatesMutex.Lock()
+	defer oauthStatesMutex.Unlock()
+	v, ok := oauthStates[state]
+	if !ok {
+		return v, false
+	}
+	delete(oauthStates, state)
+	return v, time.Now().Before(v.timeExpiry)
+}
+
+func (a *App) createOAuthState(c echo.Context) error {
+	forBot := c.QueryParam("bot") == "true"
+	return c.JSON(http.StatusOK, echo.Map{
+		"state": newOAuthState(forBot),
+	})
+}
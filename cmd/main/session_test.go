@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newTestSessionApp(t *testing.T) (*App, *echo.Echo) {
+	t.Helper()
+	a := &App{sessionSecret: "secret", launchToken: "launch"}
+	e := echo.New()
+	e.Use(a.validateHostOrigin)
+	e.GET("/session", a.handleSessionLaunch)
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/public", ok)
+	e.POST("/public", ok)
+	e.GET("/private", ok, a.requireSession)
+	return a, e
+}
+
+func serveTest(e *echo.Echo, method string, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Host = "localhost:3999"
+	for k, v := range header {
+		if k == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestValidateHostOrigin(t *testing.T) {
+	_, e := newTestSessionApp(t)
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		want   int
+	}{
+		{"localhost", http.MethodGet, nil, http.StatusOK},
+		{"loopback ip", http.MethodGet, map[string]string{"Host": "127.0.0.1:3999"}, http.StatusOK},
+		{"rebound host", http.MethodGet, map[string]string{"Host": "evil.example:3999"}, http.StatusForbidden},
+		{"other port", http.MethodGet, map[string]string{"Host": "localhost:4000"}, http.StatusForbidden},
+		{"post without origin", http.MethodPost, nil, http.StatusForbidden},
+		{"post from the control panel", http.MethodPost, map[string]string{"Origin": "http://localhost:3999"}, http.StatusOK},
+		{"post from another site", http.MethodPost, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+		{"https origin", http.MethodPost, map[string]string{"Origin": "https://localhost:3999"}, http.StatusForbidden},
+		{"get from another site", http.MethodGet, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+		{"null origin", http.MethodPost, map[string]string{"Origin": "null"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveTest(e, tt.method, "/public", tt.header); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	a, e := newTestSessionApp(t)
+	tests := []struct {
+		name   string
+		cookie string
+		want   int
+	}{
+		{"no cookie", "", http.StatusUnauthorized},
+		{"wrong cookie", sessionCookieName + "=guess", http.StatusUnauthorized},
+		{"session cookie", sessionCookieName + "=secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := map[string]string{}
+			if tt.cookie != "" {
+				header["Cookie"] = tt.cookie
+			}
+			if rec := serveTest(e, http.MethodGet, "/private", header); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// before the secret is saved an empty cookie must not match it
+	a.sessionSecret = ""
+	if rec := serveTest(e, http.MethodGet, "/private", map[string]string{"Cookie": sessionCookieName + "="}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status without a session secret = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionLaunch(t *testing.T) {
+	a, e := newTestSessionApp(t)
+	if !strings.HasSuffix(a.launchUrl(), "/session?token=launch") {
+		t.Fatalf("launchUrl() = %q", a.launchUrl())
+	}
+
+	steps := []struct {
+		name       string
+		token      string
+		want       int
+		wantCookie bool
+	}{
+		{"wrong token", "guess", http.StatusUnauthorized, false},
+		{"no token", "", http.StatusUnauthorized, false},
+		{"launch token", "launch", http.StatusFound, true},
+		{"launch token works once", "launch", http.StatusUnauthorized, false},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest(e, http.MethodGet, "/session?token="+tt.token, nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			cookies := rec.Result().Cookies()
+			if !tt.wantCookie {
+				if len(cookies) != 0 {
+					t.Errorf("got cookies %v for a rejected token", cookies)
+				}
+				return
+			}
+			if len(cookies) != 1 || cookies[0].Name != sessionCookieName || cookies[0].Value != "secret" {
+				t.Fatalf("cookies = %v, want the session secret", cookies)
+			}
+			c := cookies[0]
+			if !c.HttpOnly || c.SameSite != http.SameSiteStrictMode || c.MaxAge < int(24*time.Hour.Seconds()) {
+				t.Errorf("session cookie = %+v", c)
+			}
+		})
+	}
+}
+
+func TestPublicRequestItems(t *testing.T) {
+	now := time.Now()
+	items := []requestItem{{
+		ID:            "aaaaaaaaaaa",
+		Title:         "Song",
+		RequestedBy:   "alice",
+		RequestedByID: "100",
+		RequestedAt:   &now,
+		Source:        "chat",
+	}}
+	public := publicRequestItems(items)
+	if v := public[0]; v.RequestedByID != "" || v.RequestedAt != nil || v.Source != "" {
+		t.Errorf("publicRequestItems() kept private fields %+v", v)
+	}
+	if v := public[0]; v.RequestedBy != "alice" || v.Title != "Song" {
+		t.Errorf("publicRequestItems() dropped what overlays show %+v", v)
+	}
+	if items[0].RequestedByID != "100" {
+		t.Error("publicRequestItems() changed the control panel items")
+	}
+}
+
+func TestBroadcastSplit(t *testing.T) {
+	a := &App{clients: map[*wsClient]struct{}{}}
+	panel := &wsClient{authenticated: true, send: make(chan string, 1), done: make(chan struct{})}
+	overlay := &wsClient{send: make(chan string, 1), done: make(chan struct{})}
+	a.clients[panel] = struct{}{}
+	a.clients[overlay] = struct{}{}
+
+	a.broadcastSplit("full", "public")
+	if got := <-panel.send; got != "full" {
+		t.Errorf("control panel got %q, want full", got)
+	}
+	if got := <-overlay.send; got != "public" {
+		t.Errorf("overlay got %q, want public", got)
+	}
+
+	a.broadcastAuthenticated("private")
+	if got := <-panel.send; got != "private" {
+		t.Errorf("control panel got %q, want private", got)
+	}
+	select {
+	case got := <-overlay.send:
+		t.Errorf("overlay got %q from broadcastAuthenticated", got)
+	default:
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
 	"github.com/joeyak/go-twitch-eventsub/v3"
 	"github.com/labstack/echo/v4"
@@ -19,11 +20,12 @@ import (
 func (a *App) SetSubscriptionHandlers() {
 	a.twitchWSService.Client().OnEventStreamOnline(func(event twitch.EventStreamOnline) {
 		a.streamOnline = true
+		a.setNowPlayingThisStream(a.nowPlayingSetting(data.DB_KEY_NOW_PLAYING_ENABLED) == "true")
 
 		j, _ := json.Marshal(echo.Map{
 			"stream_online": true,
 		})
-		a.clientsBroadcast <- string(j)
+		a.broadcast(string(j))
 		log.Println("STREAM_ONLINE")
 	})
 	a.twitchWSService.Client().OnEventStreamOffline(func(event twitch.EventStreamOffline) {
@@ -31,27 +33,68 @@ func (a *App) SetSubscriptionHandlers() {
 		j, _ := json.Marshal(echo.Map{
 			"stream_online": false,
 		})
-		a.clientsBroadcast <- string(j)
+		a.broadcast(string(j))
 		log.Println("STREAM_OFFLINE")
 	})
+	a.twitchWSService.Client().OnEventChannelModeratorAdd(func(event twitch.EventChannelModeratorAdd) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+	})
+	a.twitchWSService.Client().OnEventChannelModeratorRemove(func(event twitch.EventChannelModeratorRemove) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+	})
+	a.twitchWSService.Client().OnEventChannelSubscribe(func(event twitch.EventChannelSubscribe) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+		if !event.IsGift {
+			// gifters earn through channel.subscription.gift instead
+			a.grantRequestCredits(event.UserID, event.UserLogin, a.creditSetting(data.DB_KEY_CREDITS_PER_SUB), "sub")
+		}
+	})
+	a.twitchWSService.Client().OnEventChannelSubscriptionMessage(func(event twitch.EventChannelSubscriptionMessage) {
+		a.grantRequestCredits(event.UserID, event.UserLogin, a.creditSetting(data.DB_KEY_CREDITS_PER_RESUB), "resub")
+	})
+	a.twitchWSService.Client().OnEventChannelSubscriptionGift(func(event twitch.EventChannelSubscriptionGift) {
+		if event.IsAnonymous {
+			return
+		}
+		a.grantRequestCredits(event.UserID, event.UserLogin, a.creditSetting(data.DB_KEY_CREDITS_PER_GIFTED_SUB)*event.Total, "gifted subs")
+	})
+	a.twitchWSService.Client().OnEventChannelCheer(func(event twitch.EventChannelCheer) {
+		bitsPerCredit := a.creditSetting(data.DB_KEY_CREDITS_CHEER_BITS)
+		if event.IsAnonymous || bitsPerCredit == 0 {
+			return
+		}
+		// leftover bits below the threshold are not carried over
+		a.grantRequestCredits(event.UserID, event.UserLogin, event.Bits/bitsPerCredit, "cheer")
+	})
+	a.twitchWSService.Client().OnEventChannelRaid(func(event twitch.EventChannelRaid) {
+		a.grantRequestCredits(event.FromBroadcasterUserId, event.FromBroadcasterUserLogin, a.creditSetting(data.DB_KEY_CREDITS_PER_RAID), "raid")
+	})
+	a.twitchWSService.Client().OnEventChannelSubscriptionEnd(func(event twitch.EventChannelSubscriptionEnd) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+	})
+	a.twitchWSService.Client().OnEventChannelVIPAdd(func(event twitch.EventChannelVIPAdd) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+	})
+	a.twitchWSService.Client().OnEventChannelVIPRemove(func(event twitch.EventChannelVIPRemove) {
+		mainChannelUserRolesCache.Delete(event.UserID)
+	})
 	a.twitchWSService.Client().OnEventChannelChatMessage(func(event twitch.EventChannelChatMessage) {
-		isSub := false
-		isBroadcaster := false
-		isModerator := false
-		for _, v := range event.Badges {
-			if v.SetId == "subscriber" {
-				isSub = true
-			}
-			if v.SetId == "broadcaster" {
-				isBroadcaster = true
-				isModerator = true
-				isSub = true
-			}
-			if v.SetId == "moderator" {
-				isModerator = true
-				isSub = true
+		roles := songrequests.ChatterRolesFromBadges(event.Badges)
+		// replies keep event.MessageId and event.BroadcasterUserId for partner messages too, see IsSharedChatPartnerMessage
+		isPartnerMessage := songrequests.IsSharedChatPartnerMessage(event)
+		if isPartnerMessage {
+			// Shared Chat, badges can belong to the partner channel so only trust what our channel says
+			roles = songrequests.ChatterRoles{}
+			if strings.HasPrefix(event.Message.Text, "!") {
+				var err error
+				roles, err = a.checkMainChannelUserRoles(event.ChatterUserId)
+				if err != nil {
+					log.Println("Failed to check the roles of", event.ChatterUserLogin, err)
+					roles = songrequests.ChatterRoles{}
+				}
 			}
 		}
+		isBroadcaster := roles.IsBroadcaster
 		var useProperHelix *helix.Client
 		properUserID := ""
 		if a.twitchDataStructBot.isAuthenticated {
@@ -62,35 +105,53 @@ func (a *App) SetSubscriptionHandlers() {
 			properUserID = a.twitchDataStruct.userID
 		}
 
-		log.Printf("Chat message from %s: %s %s\n", event.ChatterUserLogin, event.Message.Text, event.ChannelPointsCustomRewardId)
-		if a.songRequestRewardID == event.ChannelPointsCustomRewardId || (strings.HasPrefix(event.Message.Text, "!sr ") && isSub) {
+		if isPartnerMessage {
+			log.Printf("Shared chat message from %s in %s: %s\n", event.ChatterUserLogin, event.SourceBroadcasterUserLogin, event.Message.Text)
+		} else {
+			log.Printf("Chat message from %s: %s %s\n", event.ChatterUserLogin, event.Message.Text, event.ChannelPointsCustomRewardId)
+		}
+		isRewardRequest := event.ChannelPointsCustomRewardId != "" && a.rewardID() == event.ChannelPointsCustomRewardId
+		if isRewardRequest || strings.HasPrefix(event.Message.Text, "!sr ") {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
-			a.songRequestSubmit(useProperHelix, properUserID, event)
+			if isPartnerMessage && !a.sharedChatRequestAllowed(&roles, event.ChatterUserId) {
+				return
+			}
+			permissionKey := data.DB_KEY_PERMISSION_SR
+			if isRewardRequest {
+				permissionKey = data.DB_KEY_PERMISSION_SR_REWARD
+			}
+			usesCredit := false
+			if !a.chatterAllowed(permissionKey, &roles, event.ChatterUserId) {
+				if isRewardRequest || !a.hasRequestCredits(event.ChatterUserId) {
+					return
+				}
+				usesCredit = true
+			}
+			a.songRequestSubmit(useProperHelix, properUserID, event, usesCredit)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!credits") {
+			a.replyRequestCredits(useProperHelix, properUserID, event)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!announce") && a.chatterAllowed(data.DB_KEY_PERMISSION_ANNOUNCE, &roles, event.ChatterUserId) {
+			a.toggleNowPlayingThisStream(useProperHelix, properUserID, event)
 			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!skip") && isModerator {
+		if strings.HasPrefix(event.Message.Text, "!skip") && a.chatterAllowed(data.DB_KEY_PERMISSION_SKIP, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
-			hasSkipped := false
-			skipMutex.Lock()
-			if time.Now().After(lastSkipped.Add(time.Second * -10)) {
-				hasSkipped = true
-				songQueueMutex.Lock()
-				http.Post("http://"+songrequests.GetPearDesktopHost()+"/api/v1/next", "application/json", nil)
-				songQueueMutex.Unlock()
-				lastSkipped = time.Now()
+			s, err := a.skipSong(event.ChatterUserLogin)
+			if err != nil && err != errSkipCooldown {
+				log.Println("Failed to skip the song", err)
 			}
-			skipMutex.Unlock()
-			if hasSkipped {
-				s := "Skipped song!"
-				if songQueueMutex.TryRLock() {
-					s = "Skipped " + playerInfo.Song.AlternativeTitle + "!"
-					songQueueMutex.RUnlock()
-				}
+			if err == nil {
 				useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
 					BroadcasterID:        event.BroadcasterUserId,
 					SenderID:             properUserID,
@@ -101,15 +162,29 @@ func (a *App) SetSubscriptionHandlers() {
 			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!song") {
+		if isBlocklistCommand(event.Message.Text) && a.chatterAllowed(data.DB_KEY_PERMISSION_BLOCKLIST, &roles, event.ChatterUserId) {
+			a.replyBlocklistCommand(useProperHelix, properUserID, event)
+			return
+		}
+
+		if isStatsCommand(event.Message.Text) && a.chatterAllowed(data.DB_KEY_PERMISSION_STATS, &roles, event.ChatterUserId) {
+			if !a.streamOnline && !isBroadcaster {
+				return
+			}
+			a.replyStatsCommand(useProperHelix, properUserID, event)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!song") && a.chatterAllowed(data.DB_KEY_PERMISSION_SONG, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
 			failed := false
 			song := songrequests.SongResult{}
 			var rootErr error = nil
+			cooldown := time.Duration(a.timingSetting(data.DB_KEY_SONG_COOLDOWN_SECONDS)) * time.Second
 			currentSongMutex.Lock()
-			if time.Now().After(lastUsedCurrentSong.Add(time.Second * -10)) {
+			if time.Now().After(lastUsedCurrentSong.Add(cooldown)) {
 				lastUsedCurrentSong = time.Now()
 				resp, err := http.Get("http://" + songrequests.GetPearDesktopHost() + "/api/v1/song")
 				if err == nil {
@@ -127,6 +202,10 @@ func (a *App) SetSubscriptionHandlers() {
 					failed = true
 					rootErr = err
 				}
+			} else {
+				// answered a moment ago, stay quiet
+				currentSongMutex.Unlock()
+				return
 			}
 			currentSongMutex.Unlock()
 			if failed {
@@ -148,7 +227,7 @@ func (a *App) SetSubscriptionHandlers() {
 			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!queue") {
+		if strings.HasPrefix(event.Message.Text, "!queue") && a.chatterAllowed(data.DB_KEY_PERMISSION_QUEUE, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
@@ -177,8 +256,9 @@ func (a *App) SetSubscriptionHandlers() {
 				} `json:"items"`
 			}{}
 			var rootErr error = nil
+			cooldown := time.Duration(a.timingSetting(data.DB_KEY_QUEUE_COOLDOWN_SECONDS)) * time.Second
 			queueCmdMutex.Lock()
-			if time.Now().After(lastUsedQueueCmd.Add(time.Second * -10)) {
+			if time.Now().After(lastUsedQueueCmd.Add(cooldown)) {
 				lastUsedQueueCmd = time.Now()
 				resp, err := http.Get("http://" + songrequests.GetPearDesktopHost() + "/api/v1/queue")
 				if err == nil {
@@ -196,6 +276,10 @@ func (a *App) SetSubscriptionHandlers() {
 					failed = true
 					rootErr = err
 				}
+			} else {
+				// answered a moment ago, stay quiet
+				queueCmdMutex.Unlock()
+				return
 			}
 			queueCmdMutex.Unlock()
 			if failed {
@@ -230,6 +314,9 @@ func (a *App) SetSubscriptionHandlers() {
 					s += sl
 				}
 				s = strings.TrimSuffix(s, ", ")
+				if u := a.publicQueueUrl(); u != "" {
+					s += " | Full queue: " + u
+				}
 
 				useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
 					BroadcasterID:        event.BroadcasterUserId,
@@ -244,10 +331,10 @@ func (a *App) SetSubscriptionHandlers() {
 }
 
 var skipMutex = sync.Mutex{}
-var lastSkipped = time.Now().Add(time.Second * -10)
+var lastSkipped = time.Time{}
 
 var currentSongMutex = sync.Mutex{}
-var lastUsedCurrentSong = time.Now().Add(time.Second * -10)
+var lastUsedCurrentSong = time.Time{}
 
 var queueCmdMutex = sync.Mutex{}
-var lastUsedQueueCmd = time.Now().Add(time.Second * -10)
+var lastUsedQueueCmd = time.Time{}
@@ -10,120 +10,71 @@ import (
 	"sync"
 	"time"
 
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
 	"github.com/joeyak/go-twitch-eventsub/v3"
 	"github.com/nicklaw5/helix/v2"
 )
 
-var checkMainChannelUserStatusMutex = sync.RWMutex{}
-var checkMainChannelUserStatus = map[string]struct {
-	isSub       bool
-	isModerator bool
-	timeExpiry  time.Time
-}{}
-
 func (a *App) SetSubscriptionHandlersBot() {
 	a.twitchWSBotService.Client().OnEventChannelChatMessage(func(event twitch.EventChannelChatMessage) {
-		isSub := false
-		isBroadcaster := false
-		isModerator := false
 		useProperHelix := a.helixBot
 		properUserID := a.twitchDataStructBot.userID
-		realBroadcasterID := a.twitchDataStruct.userID
 
+		// every command starts with !, other messages are not worth the Helix role lookups
+		if !strings.HasPrefix(event.Message.Text, "!") {
+			return
+		}
+		roles := songrequests.ChatterRoles{}
 		if strings.EqualFold(event.ChatterUserLogin, a.twitchDataStruct.login) {
-			isSub = true
-			isBroadcaster = true
-			isModerator = true
+			roles.IsBroadcaster = true
 		} else {
-			checkMainChannelUserStatusMutex.RLock()
-			if v, ok := checkMainChannelUserStatus[event.ChatterUserLogin]; ok && !time.Now().After(v.timeExpiry) {
-				isSub = v.isSub
-				isModerator = v.isModerator
-				checkMainChannelUserStatusMutex.RUnlock()
-			} else {
-				checkMainChannelUserStatusMutex.RUnlock()
-
-				subsResponse, err := a.helix.GetSubscriptions(&helix.SubscriptionsParams{
-					UserID:        []string{event.ChatterUserId},
-					BroadcasterID: realBroadcasterID,
-				})
-				if err != nil {
-					emsg := "Internal error when checking if you are a sub"
-					log.Println(emsg, err)
-					a.helixBot.SendChatMessage(&helix.SendChatMessageParams{
-						BroadcasterID:        event.BroadcasterUserId,
-						SenderID:             properUserID,
-						Message:              emsg,
-						ReplyParentMessageID: event.MessageId,
-					})
-					return
-				}
-				if len(subsResponse.Data.Subscriptions) > 0 {
-					isSub = true
-				}
-
-				modsResponse, err := a.helix.GetModerators(&helix.GetModeratorsParams{
-					UserIDs:       []string{event.ChatterUserId},
-					BroadcasterID: realBroadcasterID,
-				})
-				if err != nil {
-					emsg := "Internal error when checking if you are a moderator"
-					log.Println(emsg, err)
-					a.helixBot.SendChatMessage(&helix.SendChatMessageParams{
-						BroadcasterID:        event.BroadcasterUserId,
-						SenderID:             properUserID,
-						Message:              emsg,
-						ReplyParentMessageID: event.MessageId,
-					})
-					return
-				}
-				if len(modsResponse.Data.Moderators) > 0 {
-					isSub = true
-					isModerator = true
-				}
-
-				checkMainChannelUserStatusMutex.Lock()
-				checkMainChannelUserStatus[event.ChatterUserLogin] = struct {
-					isSub       bool
-					isModerator bool
-					timeExpiry  time.Time
-				}{
-					isSub:       isSub,
-					isModerator: isModerator,
-					timeExpiry:  time.Now().Add(time.Hour * 2),
-				}
-				checkMainChannelUserStatusMutex.Unlock()
+			var err error
+			roles, err = a.checkMainChannelUserRoles(event.ChatterUserId)
+			if err != nil {
+				log.Println("Failed to check the roles of", event.ChatterUserLogin, err)
+				return
 			}
 		}
+		isBroadcaster := roles.IsBroadcaster
 
-		if strings.HasPrefix(event.Message.Text, "!sr ") && isSub {
+		if strings.HasPrefix(event.Message.Text, "!sr ") {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
-			a.songRequestSubmit(useProperHelix, properUserID, event)
+			if songrequests.IsSharedChatPartnerMessage(event) && !a.sharedChatRequestAllowed(&roles, event.ChatterUserId) {
+				return
+			}
+			usesCredit := false
+			if !a.chatterAllowed(data.DB_KEY_PERMISSION_SR, &roles, event.ChatterUserId) {
+				if !a.hasRequestCredits(event.ChatterUserId) {
+					return
+				}
+				usesCredit = true
+			}
+			a.songRequestSubmit(useProperHelix, properUserID, event, usesCredit)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!credits") {
+			a.replyRequestCredits(useProperHelix, properUserID, event)
+			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!skip") && isModerator {
+		if strings.HasPrefix(event.Message.Text, "!announce") && a.chatterAllowed(data.DB_KEY_PERMISSION_ANNOUNCE, &roles, event.ChatterUserId) {
+			a.toggleNowPlayingThisStream(useProperHelix, properUserID, event)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!skip") && a.chatterAllowed(data.DB_KEY_PERMISSION_SKIP, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
-			hasSkipped := false
-			skipMutex.Lock()
-			if time.Now().After(lastSkipped.Add(time.Second * -10)) {
-				hasSkipped = true
-				songQueueMutex.Lock()
-				http.Post("http://"+songrequests.GetPearDesktopHost()+"/api/v1/next", "application/json", nil)
-				songQueueMutex.Unlock()
-				lastSkipped = time.Now()
+			s, err := a.skipSong(event.ChatterUserLogin)
+			if err != nil && err != errSkipCooldown {
+				log.Println("Failed to skip the song", err)
 			}
-			skipMutex.Unlock()
-			if hasSkipped {
-				s := "Skipped song!"
-				if songQueueMutex.TryRLock() {
-					s = "Skipped " + playerInfo.Song.AlternativeTitle + "!"
-					songQueueMutex.RUnlock()
-				}
+			if err == nil {
 				a.helixBot.SendChatMessage(&helix.SendChatMessageParams{
 					BroadcasterID:        event.BroadcasterUserId,
 					SenderID:             properUserID,
@@ -134,15 +85,29 @@ func (a *App) SetSubscriptionHandlersBot() {
 			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!song") {
+		if isBlocklistCommand(event.Message.Text) && a.chatterAllowed(data.DB_KEY_PERMISSION_BLOCKLIST, &roles, event.ChatterUserId) {
+			a.replyBlocklistCommand(useProperHelix, properUserID, event)
+			return
+		}
+
+		if isStatsCommand(event.Message.Text) && a.chatterAllowed(data.DB_KEY_PERMISSION_STATS, &roles, event.ChatterUserId) {
+			if !a.streamOnline && !isBroadcaster {
+				return
+			}
+			a.replyStatsCommand(useProperHelix, properUserID, event)
+			return
+		}
+
+		if strings.HasPrefix(event.Message.Text, "!song") && a.chatterAllowed(data.DB_KEY_PERMISSION_SONG, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
 			failed := false
 			song := songrequests.SongResult{}
 			var rootErr error = nil
+			cooldown := time.Duration(a.timingSetting(data.DB_KEY_SONG_COOLDOWN_SECONDS)) * time.Second
 			currentSongMutexBot.Lock()
-			if time.Now().After(lastUsedCurrentSongBot.Add(time.Second * -10)) {
+			if time.Now().After(lastUsedCurrentSongBot.Add(cooldown)) {
 				lastUsedCurrentSongBot = time.Now()
 				resp, err := http.Get("http://" + songrequests.GetPearDesktopHost() + "/api/v1/song")
 				if err == nil {
@@ -160,6 +125,10 @@ func (a *App) SetSubscriptionHandlersBot() {
 					failed = true
 					rootErr = err
 				}
+			} else {
+				// answered a moment ago, stay quiet
+				currentSongMutexBot.Unlock()
+				return
 			}
 			currentSongMutexBot.Unlock()
 			if failed {
@@ -181,7 +150,7 @@ func (a *App) SetSubscriptionHandlersBot() {
 			return
 		}
 
-		if strings.HasPrefix(event.Message.Text, "!queue") {
+		if strings.HasPrefix(event.Message.Text, "!queue") && a.chatterAllowed(data.DB_KEY_PERMISSION_QUEUE, &roles, event.ChatterUserId) {
 			if !a.streamOnline && !isBroadcaster {
 				return
 			}
@@ -210,8 +179,9 @@ func (a *App) SetSubscriptionHandlersBot() {
 				} `json:"items"`
 			}{}
 			var rootErr error = nil
+			cooldown := time.Duration(a.timingSetting(data.DB_KEY_QUEUE_COOLDOWN_SECONDS)) * time.Second
 			queueCmdMutexBot.Lock()
-			if time.Now().After(lastUsedQueueCmdBot.Add(time.Second * -10)) {
+			if time.Now().After(lastUsedQueueCmdBot.Add(cooldown)) {
 				lastUsedQueueCmdBot = time.Now()
 				resp, err := http.Get("http://" + songrequests.GetPearDesktopHost() + "/api/v1/queue")
 				if err == nil {
@@ -229,6 +199,10 @@ func (a *App) SetSubscriptionHandlersBot() {
 					failed = true
 					rootErr = err
 				}
+			} else {
+				// answered a moment ago, stay quiet
+				queueCmdMutexBot.Unlock()
+				return
 			}
 			queueCmdMutexBot.Unlock()
 			if failed {
@@ -263,6 +237,9 @@ func (a *App) SetSubscriptionHandlersBot() {
 					s += sl
 				}
 				s = strings.TrimSuffix(s, ", ")
+				if u := a.publicQueueUrl(); u != "" {
+					s += " | Full queue: " + u
+				}
 
 				a.helixBot.SendChatMessage(&helix.SendChatMessageParams{
 					BroadcasterID:        event.BroadcasterUserId,
@@ -277,7 +254,7 @@ func (a *App) SetSubscriptionHandlersBot() {
 }
 
 var currentSongMutexBot = sync.Mutex{}
-var lastUsedCurrentSongBot = time.Now().Add(time.Second * -10)
+var lastUsedCurrentSongBot = time.Time{}
 
 var queueCmdMutexBot = sync.Mutex{}
-var lastUsedQueueCmdBot = time.Now().Add(time.Second * -10)
+var lastUsedQueueCmdBot = time.Time{}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+)
+
+type settingType = string
+
+const (
+	settingTypeString settingType = "string"
+	settingTypeInt    settingType = "int"
+	settingTypeBool   settingType = "bool"
+	settingTypeEnum   settingType = "enum"
+	// comma separated chatter roles
+	settingTypeRoles settingType = "roles"
+)
+
+// settingDef describes one key of the settings table that can be changed from the control panel,
+// set applies the value live and get reads what is in effect now
+type settingDef struct {
+	Key         string      `json:"key"`
+	Type        settingType `json:"type"`
+	Default     string      `json:"default"`
+	Min         *int        `json:"min,omitempty"`
+	Max         *int        `json:"max,omitempty"`
+	Options     []string    `json:"options,omitempty"`
+	Description string      `json:"description"`
+	// check covers what the type cannot say, like paths and URLs, so it runs before anything is applied
+	check func(value string) error
+	set   func(a *App, value string) error
+	get   func(a *App) string
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func permissionSettingDef(key string, description string) settingDef {
+	return settingDef{
+		Key:         key,
+		Type:        settingTypeRoles,
+		Default:     defaultPermissionRules[key].String(),
+		Description: description,
+		set:         func(a *App, v string) error { return a.setPermissionRule(key, v) },
+		get:         func(a *App) string { return a.permissionRule(key).String() },
+	}
+}
+
+func creditSettingDef(key string, description string) settingDef {
+	return settingDef{
+		Key:         key,
+		Type:        settingTypeInt,
+		Default:     strconv.Itoa(defaultCreditSettings[key]),
+		Min:         intPtr(0),
+		Max:         intPtr(100000),
+		Description: description,
+		set:         func(a *App, v string) error { return a.setCreditSetting(key, v) },
+		get:         func(a *App) string { return strconv.Itoa(a.creditSetting(key)) },
+	}
+}
+
+func nowPlayingSettingDef(key string, t settingType, options []string, description string) settingDef {
+	return settingDef{
+		Key:         key,
+		Type:        t,
+		Default:     defaultNowPlayingSettings[key],
+		Options:     options,
+		Description: description,
+		check:       func(v string) error { return checkNowPlayingSetting(key, v) },
+		set:         func(a *App, v string) error { return a.setNowPlayingSetting(key, v) },
+		get:         func(a *App) string { return a.nowPlayingSetting(key) },
+	}
+}
+
+func fileOutputSettingDef(key string, t settingType, description string) settingDef {
+	d := settingDef{
+		Key:         key,
+		Type:        t,
+		Default:     defaultFileOutputSettings[key],
+		Description: description,
+		check:       func(v string) error { return checkFileOutputSetting(key, v) },
+		set:         func(a *App, v string) error { return a.setFileOutputSetting(key, v) },
+		get:         func(a *App) string { return a.fileOutputSetting(key) },
+	}
+	if t == settingTypeInt {
+		d.Min, d.Max = intPtr(1), intPtr(50)
+	}
+	return d
+}
+
+func publicQueueSettingDef(key string, t settingType, description string) settingDef {
+	d := settingDef{
+		Key:         key,
+		Type:        t,
+		Default:     defaultPublicQueueSettings[key],
+		Description: description,
+		check:       func(v string) error { return checkPublicQueueSetting(key, v) },
+		set:         func(a *App, v string) error { return a.setPublicQueueSetting(key, v) },
+		get:         func(a *App) string { return a.publicQueueSetting(key) },
+	}
+	if t == settingTypeInt {
+		d.Min, d.Max = intPtr(1), intPtr(600)
+	}
+	return d
+}
+
+func timingSettingDef(key string, min int, max int, description string) settingDef {
+	return settingDef{
+		Key:         key,
+		Type:        settingTypeInt,
+		Default:     strconv.Itoa(defaultTimingSettings[key]),
+		Min:         intPtr(min),
+		Max:         intPtr(max),
+		Description: description,
+		set:         func(a *App, v string) error { return a.setTimingSetting(key, v) },
+		get:         func(a *App) string { return strconv.Itoa(a.timingSetting(key)) },
+	}
+}
+
+func (a *App) rewardID() string {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.songRequestRewardID
+}
+
+var settingDefs = []settingDef{
+	{
+		Key:         data.DB_KEY_TWITCH_SONG_REQUEST_REWARD_ID,
+		Type:        settingTypeString,
+		Description: "Channel points reward ID that requests a song, empty turns reward requests off",
+		set: func(a *App, v string) error {
+			a.settingsMu.Lock()
+			a.songRequestRewardID = strings.TrimSpace(v)
+			a.settingsMu.Unlock()
+			return nil
+		},
+		get: (*App).rewardID,
+	},
+	permissionSettingDef(data.DB_KEY_PERMISSION_SR, "Roles that can use !sr"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_SR_REWARD, "Roles that can request with the channel points reward"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_SKIP, "Roles that can use !skip"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_SONG, "Roles that can use !song"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_QUEUE, "Roles that can use !queue"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_ANNOUNCE, "Roles that can use !announce"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_STATS, "Roles that can use !topsongs, !topartists, !toprequesters and !mystats"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_BLOCKLIST, "Roles that can use !srban, !srblock and !srunblock"),
+	permissionSettingDef(data.DB_KEY_PERMISSION_SHARED_CHAT, "Roles partner channel chatters need in our channel to request when shared_chat_requests is restrict"),
+	{
+		Key:         data.DB_KEY_FOLLOWER_MIN_AGE_MINUTES,
+		Type:        settingTypeInt,
+		Default:     "0",
+		Min:         intPtr(0),
+		Max:         intPtr(525600),
+		Description: "Minutes a chatter must have followed for to count as a follower",
+		set:         (*App).setFollowerMinAge,
+		get: func(a *App) string {
+			a.settingsMu.RLock()
+			defer a.settingsMu.RUnlock()
+			return strconv.Itoa(int(a.followerMinAge / time.Minute))
+		},
+	},
+	{
+		Key:         data.DB_KEY_SHARED_CHAT_REQUESTS,
+		Type:        settingTypeEnum,
+		Default:     songrequests.SharedChatRequestModeDeny,
+		Options:     []string{songrequests.SharedChatRequestModeAllow, songrequests.SharedChatRequestModeDeny, songrequests.SharedChatRequestModeRestrict},
+		Description: "Requests from Shared Chat partner channels, restrict also requires the roles in permission_shared_chat",
+		set:         (*App).setSharedChatRequestMode,
+		get:         (*App).sharedChatRequestMode,
+	},
+	{
+		Key:         data.DB_KEY_REQUESTS_REQUIRE_APPROVAL,
+		Type:        settingTypeBool,
+		Default:     "false",
+		Description: "Hold requests until they are approved from the control panel",
+		set:         (*App).setRequireApproval,
+		get:         func(a *App) string { return strconv.FormatBool(a.requestsRequireApproval()) },
+	},
+	creditSettingDef(data.DB_KEY_CREDITS_CHEER_BITS, "Bits cheered for one request credit, 0 turns it off"),
+	creditSettingDef(data.DB_KEY_CREDITS_PER_SUB, "Request credits for a new sub, 0 turns it off"),
+	creditSettingDef(data.DB_KEY_CREDITS_PER_GIFTED_SUB, "Request credits per gifted sub for the gifter, 0 turns it off"),
+	creditSettingDef(data.DB_KEY_CREDITS_PER_RESUB, "Request credits for a resub message, 0 turns it off"),
+	creditSettingDef(data.DB_KEY_CREDITS_PER_RAID, "Request credits for the raider, 0 turns it off"),
+	nowPlayingSettingDef(data.DB_KEY_NOW_PLAYING_ENABLED, settingTypeBool, nil, "Announce songs in chat when they start playing"),
+	nowPlayingSettingDef(data.DB_KEY_NOW_PLAYING_MODE, settingTypeEnum, []string{nowPlayingModeRequests, nowPlayingModeAll}, "Announce only requested songs or every song"),
+	nowPlayingSettingDef(data.DB_KEY_NOW_PLAYING_METHOD, settingTypeEnum, []string{nowPlayingMethodMessage, nowPlayingMethodAnnouncement}, "Announce with a chat message or a highlighted announcement"),
+	nowPlayingSettingDef(data.DB_KEY_NOW_PLAYING_TEMPLATE, settingTypeString, nil, "Announcement for requested songs, accepts {title}, {artist}, {link} and {requester}"),
+	nowPlayingSettingDef(data.DB_KEY_NOW_PLAYING_TEMPLATE_FILLER, settingTypeString, nil, "Announcement for songs nobody requested, accepts {title}, {artist} and {link}"),
+	fileOutputSettingDef(data.DB_KEY_FILE_OUTPUT_DIR, settingTypeString, "Folder for OBS text files, relative paths are inside the data directory, empty turns them off"),
+	fileOutputSettingDef(data.DB_KEY_FILE_OUTPUT_NOW_PLAYING, settingTypeString, "Content of now_playing.txt, accepts {title}, {artist}, {link} and {requester}"),
+	fileOutputSettingDef(data.DB_KEY_FILE_OUTPUT_REQUESTER, settingTypeString, "Content of requester.txt, empty for songs nobody requested"),
+	fileOutputSettingDef(data.DB_KEY_FILE_OUTPUT_QUEUE, settingTypeString, "One line of queue.txt, also accepts {position}"),
+	fileOutputSettingDef(data.DB_KEY_FILE_OUTPUT_QUEUE_COUNT, settingTypeInt, "Number of requests in queue.txt"),
+	publicQueueSettingDef(data.DB_KEY_PUBLIC_QUEUE_ENABLED, settingTypeBool, "Serve a read only queue page for viewers on its own address"),
+	publicQueueSettingDef(data.DB_KEY_PUBLIC_QUEUE_ADDRESS, settingTypeString, "Host and port the queue page listens on, only this computer by default, 0.0.0.0 makes it reachable from other devices"),
+	publicQueueSettingDef(data.DB_KEY_PUBLIC_QUEUE_BASE_URL, settingTypeString, "URL viewers open the queue page at, !queue links it when set"),
+	publicQueueSettingDef(data.DB_KEY_PUBLIC_QUEUE_RATE_LIMIT, settingTypeInt, "Requests per minute one viewer can make to the queue page"),
+	timingSettingDef(data.DB_KEY_SONG_MIN_DURATION_SECONDS, 0, 3600, "Shortest song !sr accepts, in seconds"),
+	timingSettingDef(data.DB_KEY_SONG_MAX_DURATION_SECONDS, 1, 36000, "Longest song !sr accepts, in seconds"),
+	timingSettingDef(data.DB_KEY_SKIP_COOLDOWN_SECONDS, 0, 600, "Seconds between two skips"),
+	timingSettingDef(data.DB_KEY_SONG_COOLDOWN_SECONDS, 0, 600, "Seconds !song stays quiet after answering"),
+	timingSettingDef(data.DB_KEY_QUEUE_COOLDOWN_SECONDS, 0, 600, "Seconds !queue stays quiet after answering"),
+	timingSettingDef(data.DB_KEY_SONG_END_GUARD_SECONDS, 0, 30, "Requests wait for the next song when the current one ends within this many seconds"),
+	timingSettingDef(data.DB_KEY_ROLE_CACHE_TTL_MINUTES, 1, 1440, "Minutes chatter roles and follows are cached for"),
+	timingSettingDef(data.DB_KEY_SEARCH_CACHE_TTL_HOURS, 0, 8760, "Hours a !sr search result is reused before searching again, 0 always searches"),
+}
+
+func findSettingDef(key string) (settingDef, bool) {
+	for _, v := range settingDefs {
+		if v.Key == key {
+			return v, true
+		}
+	}
+	return settingDef{}, false
+}
+
+// validate checks the value against the type and the check of the setting, nothing is applied
+func (d settingDef) validate(value string) error {
+	switch d.Type {
+	case settingTypeInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New(d.Key + " must be a number")
+		}
+		if (d.Min != nil && n < *d.Min) || (d.Max != nil && n > *d.Max) {
+			return errors.New(d.Key + " must be from " + strconv.Itoa(*d.Min) + " to " + strconv.Itoa(*d.Max))
+		}
+	case settingTypeBool:
+		if value != "true" && value != "false" {
+			return errors.New(d.Key + " must be true or false")
+		}
+	case settingTypeEnum:
+		if !slices.Contains(d.Options, value) {
+			return errors.New(d.Key + " must be one of " + strings.Join(d.Options, ", "))
+		}
+	case settingTypeRoles:
+		if _, err := songrequests.ParsePermissionRule(value); err != nil {
+			return err
+		}
+	}
+	if d.check != nil {
+		return d.check(value)
+	}
+	return nil
+}
+
+// validateSettings checks a batch of changes against the registry and against each other
+// so either all of them can be applied or none is
+func (a *App) validateSettings(settings map[string]string) error {
+	for k, v := range settings {
+		d, ok := findSettingDef(k)
+		if !ok {
+			return errors.New("unknown setting " + k)
+		}
+		if err := d.validate(v); err != nil {
+			return err
+		}
+	}
+	minSeconds, maxSeconds := a.songDurationBounds()
+	if v, ok := settings[data.DB_KEY_SONG_MIN_DURATION_SECONDS]; ok {
+		minSeconds, _ = strconv.Atoi(v)
+	}
+	if v, ok := settings[data.DB_KEY_SONG_MAX_DURATION_SECONDS]; ok {
+		maxSeconds, _ = strconv.Atoi(v)
+	}
+	if minSeconds > maxSeconds {
+		return errors.New(data.DB_KEY_SONG_MIN_DURATION_SECONDS + " cannot be more than " + data.DB_KEY_SONG_MAX_DURATION_SECONDS)
+	}
+	return nil
+}
+
+// applySetting validates and applies settings with live effect, false means the key is not one of them
+func (a *App) applySetting(k string, v string) (bool, error) {
+	d, ok := findSettingDef(k)
+	if !ok {
+		return false, nil
+	}
+	if err := d.validate(v); err != nil {
+		return true, err
+	}
+	return true, d.set(a, v)
+}
+
+type settingValue struct {
+	settingDef
+	Value string `json:"value"`
+}
+
+func (a *App) settingValues() []settingValue {
+	values := []settingValue{}
+	for _, v := range settingDefs {
+		values = append(values, settingValue{
+			settingDef: v,
+			Value:      v.get(a),
+		})
+	}
+	return values
+}
@@ -1,17 +1,24 @@
 package main
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
 )
 
 var songQueueMutex = sync.RWMutex{}
 
-var songQueue = []struct {
-	requestedBy string
-	song        songrequests.SongResult
-}{}
+type songQueueItem struct {
+	requestedBy   string
+	requestedByID string
+	requestedAt   time.Time
+	source        songrequests.RequestSource
+	song          songrequests.SongResult
+}
+
+var songQueue = []songQueueItem{}
 
 type playerSonginfo struct {
 	VideoId          string `json:"videoId"`
@@ -26,9 +33,31 @@ func (s playerSonginfo) GetUrl() string {
 }
 
 var playerInfo = struct {
-	Position  int
-	IsPlaying bool
-	Song      playerSonginfo `json:"song"`
+	Position    int
+	IsPlaying   bool
+	Song        playerSonginfo `json:"song"`
+	RequestedBy string
 }{
 	Song: playerSonginfo{},
 }
+
+// reorderSongQueue follows the order Pear Desktop will play requests in and drops requests no longer queued.
+// Caller must hold songQueueMutex.
+func reorderSongQueue(queue *songrequests.PearQueue) {
+	indexes := map[string]int{}
+	for _, v := range songQueue {
+		if i := queue.IndexAfterSelected(v.song.VideoID); i != -1 {
+			indexes[v.song.VideoID] = i
+		}
+	}
+	newQueue := []songQueueItem{}
+	for _, v := range songQueue {
+		if _, ok := indexes[v.song.VideoID]; ok {
+			newQueue = append(newQueue, v)
+		}
+	}
+	sort.SliceStable(newQueue, func(i, j int) bool {
+		return indexes[newQueue[i].song.VideoID] < indexes[newQueue[j].song.VideoID]
+	})
+	songQueue = newQueue
+}
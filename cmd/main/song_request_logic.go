@@ -1,6 +1,5 @@
 package main
 
-//lint:file-ignore ST1001 Dot imports by jet
 import (
 	"bytes"
 	"encoding/json"
@@ -11,10 +10,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
-	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
-	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
 	"github.com/joeyak/go-twitch-eventsub/v3"
 	"github.com/labstack/echo/v4"
@@ -22,13 +18,14 @@ import (
 )
 
 var srChan = make(chan struct {
-	song  *songrequests.SongResult
-	event twitch.EventChannelChatMessage
+	song       *songrequests.SongResult
+	event      twitch.EventChannelChatMessage
+	usedCredit bool
 })
 
-func (a *App) songRequestLogic(song *songrequests.SongResult, event twitch.EventChannelChatMessage) {
+func (a *App) songRequestLogic(song *songrequests.SongResult, event twitch.EventChannelChatMessage, usedCredit bool) {
 	// Check if song ends <4s to prevent player state changes timing fkup
-	a.safeLockMutexWaitForSongEnds(4)
+	a.safeLockMutexWaitForSongEnds(a.timingSetting(data.DB_KEY_SONG_END_GUARD_SECONDS))
 	defer songQueueMutex.Unlock()
 
 	var useProperHelix *helix.Client
@@ -44,6 +41,10 @@ func (a *App) songRequestLogic(song *songrequests.SongResult, event twitch.Event
 	for _, v := range songQueue {
 		if song.VideoID == v.song.VideoID {
 			// Song was added too fast, between internal api calls
+			if usedCredit {
+				a.refundRequestCredit(event.ChatterUserId)
+			}
+			go a.requestRejected(event, song, requestRejectedAlreadyQueued)
 			return
 		}
 	}
@@ -57,6 +58,10 @@ func (a *App) songRequestLogic(song *songrequests.SongResult, event twitch.Event
 	if err != nil || resp.StatusCode != http.StatusNoContent {
 		emsg := "Internal error when adding song to queue. Disregard previous message."
 		log.Println(emsg, err)
+		if usedCredit {
+			a.refundRequestCredit(event.ChatterUserId)
+		}
+		go a.requestRejected(event, song, requestRejectedError)
 		useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
 			BroadcasterID:        event.BroadcasterUserId,
 			SenderID:             properUserID,
@@ -78,45 +83,19 @@ func (a *App) songRequestLogic(song *songrequests.SongResult, event twitch.Event
 	if len(songQueue) > 0 {
 		afterVideoId = songQueue[len(songQueue)-1].song.VideoID
 	}
-	songQueue = append(songQueue, struct {
-		requestedBy string
-		song        songrequests.SongResult
-	}{
-		requestedBy: event.ChatterUserLogin,
-		song:        *song,
-	})
+	item := songQueueItem{
+		requestedBy:   event.ChatterUserLogin,
+		requestedByID: event.ChatterUserId,
+		requestedAt:   time.Now(),
+		source:        requestSource(event),
+		song:          *song,
+	}
+	songQueue = append(songQueue, item)
+	go a.broadcastRequestAdded(item)
+	go a.broadcastRequests()
 
 	// save to history
-	go func() {
-		db, err := databaseconn.NewDBConnection()
-		if err != nil {
-			log.Println("Somehow failed to add !sr history to database")
-			return
-		}
-		srData := model.SongRequests{
-			VideoID:    song.VideoID,
-			SongTitle:  song.Title,
-			ArtistName: song.Artist,
-			ImageURL:   song.ImageUrl,
-		}
-		stmt := SongRequests.INSERT(SongRequests.AllColumns).MODEL(srData).ON_CONFLICT(SongRequests.VideoID).DO_NOTHING()
-		_, err = stmt.Exec(db)
-		if err != nil {
-			log.Println("Somehow failed to save !sr song to database")
-			return
-		}
-		srrData := model.SongRequestRequesters{
-			VideoID:        song.VideoID,
-			TwitchUsername: event.ChatterUserLogin,
-			RequestedAt:    time.Now().Local().Format(data.TWITCH_SERVER_DATE_LAYOUT),
-		}
-		stmt = SongRequestRequesters.INSERT(SongRequestRequesters.AllColumns).MODEL(srrData)
-		_, err = stmt.Exec(db)
-		if err != nil {
-			log.Println("Somehow failed to save !sr requester name to database")
-			return
-		}
-	}()
+	go a.saveRequestHistory(song, event, songrequests.RequestOutcomeQueued, "")
 
 	// Fetch new q details
 	// Get q info
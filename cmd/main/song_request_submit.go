@@ -11,10 +11,37 @@ import (
 	"github.com/nicklaw5/helix/v2"
 )
 
-func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage) {
+func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage, usesCredit bool) {
+	if !a.requestsOpen() {
+		useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+			BroadcasterID:        event.BroadcasterUserId,
+			SenderID:             properUserID,
+			Message:              "Song requests are closed right now.",
+			ReplyParentMessageID: event.MessageId,
+		})
+		go a.requestRejected(event, nil, requestRejectedClosed)
+		return
+	}
+	if a.isRequestBanned(event.ChatterUserId) {
+		go a.requestRejected(event, nil, requestRejectedBanned)
+		return
+	}
+
 	s := songrequests.ParseSearchQuery(event.Message.Text)
-	song, err := songrequests.SearchSong(s, 60, 600)
+	minDuration, maxDuration := a.songDurationBounds()
+	song, err := a.searchSong(s, minDuration, maxDuration)
 	if err != nil {
+		go a.requestRejected(event, nil, requestRejectedNotFound)
+		return
+	}
+	if _, blocked := a.songBlocked(song); blocked {
+		useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+			BroadcasterID:        event.BroadcasterUserId,
+			SenderID:             properUserID,
+			Message:              "That song can't be requested.",
+			ReplyParentMessageID: event.MessageId,
+		})
+		go a.requestRejected(event, song, requestRejectedBlocked)
 		return
 	}
 
@@ -53,6 +80,7 @@ func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID strin
 			Message:              emsg,
 			ReplyParentMessageID: event.MessageId,
 		})
+		go a.requestRejected(event, song, requestRejectedError)
 		return
 	}
 	qb, err := io.ReadAll(preResponse.Body)
@@ -65,6 +93,7 @@ func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID strin
 			Message:              emsg,
 			ReplyParentMessageID: event.MessageId,
 		})
+		go a.requestRejected(event, song, requestRejectedError)
 		return
 	}
 	err = json.Unmarshal(qb, &queue)
@@ -78,6 +107,7 @@ func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID strin
 			Message:              emsg,
 			ReplyParentMessageID: event.MessageId,
 		})
+		go a.requestRejected(event, song, requestRejectedError)
 		return
 	}
 
@@ -101,6 +131,39 @@ func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID strin
 			Message:              msg,
 			ReplyParentMessageID: event.MessageId,
 		})
+		go a.requestRejected(event, song, requestRejectedAlreadyQueued)
+		return
+	}
+
+	if usesCredit {
+		ok, err := a.consumeRequestCredit(event.ChatterUserId)
+		if err != nil || !ok {
+			emsg := "You have no song request credits left!"
+			reason := requestRejectedNoCredits
+			if err != nil {
+				reason = requestRejectedError
+				emsg = "Internal error when using your song request credit"
+				log.Println(emsg, err)
+			}
+			useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+				BroadcasterID:        event.BroadcasterUserId,
+				SenderID:             properUserID,
+				Message:              emsg,
+				ReplyParentMessageID: event.MessageId,
+			})
+			go a.requestRejected(event, song, reason)
+			return
+		}
+	}
+
+	if a.requestsRequireApproval() {
+		a.addPendingRequest(song, event, usesCredit)
+		useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+			BroadcasterID:        event.BroadcasterUserId,
+			SenderID:             properUserID,
+			Message:              "Waiting for approval: " + song.Title + " - " + song.Artist + " " + "https://youtu.be/" + song.VideoID,
+			ReplyParentMessageID: event.MessageId,
+		})
 		return
 	}
 
@@ -112,10 +175,12 @@ func (a *App) songRequestSubmit(useProperHelix *helix.Client, properUserID strin
 		ReplyParentMessageID: event.MessageId,
 	})
 	srChan <- struct {
-		song  *songrequests.SongResult
-		event twitch.EventChannelChatMessage
+		song       *songrequests.SongResult
+		event      twitch.EventChannelChatMessage
+		usedCredit bool
 	}{
-		song:  song,
-		event: event,
+		song:       song,
+		event:      event,
+		usedCredit: usesCredit,
 	}
 }
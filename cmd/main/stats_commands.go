@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/utils"
+	"github.com/joeyak/go-twitch-eventsub/v3"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Twitch rejects chat messages longer than this
+const chatMessageMaxLength = 500
+
+const statsTopLimit = 10
+
+var statsPeriods = map[string]struct {
+	label string
+	since func() time.Time
+}{
+	"all": {"all time", func() time.Time { return time.Time{} }},
+	"today": {"today", func() time.Time {
+		y, m, d := time.Now().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}},
+	"week":  {"past 7 days", func() time.Time { return time.Now().AddDate(0, 0, -7) }},
+	"month": {"past 30 days", func() time.Time { return time.Now().AddDate(0, 0, -30) }},
+	"year":  {"past year", func() time.Time { return time.Now().AddDate(-1, 0, 0) }},
+}
+
+var errUnknownStatsPeriod = errors.New("period must be one of all, today, week, month or year")
+
+type statsSnapshot struct {
+	Period        string                 `json:"period"`
+	Label         string                 `json:"label"`
+	TopSongs      []store.SongCount      `json:"top_songs"`
+	TopArtists    []store.ArtistCount    `json:"top_artists"`
+	TopRequesters []store.RequesterCount `json:"top_requesters"`
+}
+
+// stats are cached briefly so a chat spamming !topsongs does not run the aggregates every time
+var statsCache = utils.NewLRUCache[string, statsSnapshot](16, time.Minute)
+var userStatsCache = utils.NewLRUCache[string, store.UserStats](256, time.Minute)
+
+func (a *App) statsSnapshot(period string) (statsSnapshot, error) {
+	if period == "" {
+		period = "all"
+	}
+	p, ok := statsPeriods[period]
+	if !ok {
+		return statsSnapshot{}, errUnknownStatsPeriod
+	}
+	if v, ok := statsCache.Get(period); ok {
+		return v, nil
+	}
+	since := p.since()
+	snapshot := statsSnapshot{
+		Period: period,
+		Label:  p.label,
+	}
+	var err error
+	snapshot.TopSongs, err = a.store.Stats.TopSongs(a.ctx, since, statsTopLimit)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.TopArtists, err = a.store.Stats.TopArtists(a.ctx, since, statsTopLimit)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.TopRequesters, err = a.store.Stats.TopRequesters(a.ctx, since, statsTopLimit)
+	if err != nil {
+		return snapshot, err
+	}
+	statsCache.Set(period, snapshot)
+	return snapshot, nil
+}
+
+func (a *App) userStats(userID string, userLogin string, period string) (store.UserStats, string, error) {
+	if period == "" {
+		period = "all"
+	}
+	p, ok := statsPeriods[period]
+	if !ok {
+		return store.UserStats{}, "", errUnknownStatsPeriod
+	}
+	// the login matches requests saved before user IDs were, so it is part of the result
+	key := userID + "|" + strings.ToLower(userLogin) + "|" + period
+	if v, ok := userStatsCache.Get(key); ok {
+		return v, p.label, nil
+	}
+	stats, err := a.store.Stats.User(a.ctx, userID, userLogin, p.since())
+	if err != nil {
+		return stats, p.label, err
+	}
+	userStatsCache.Set(key, stats)
+	return stats, p.label, nil
+}
+
+// fitChatMessage adds as many of the numbered parts as fit in one chat message
+func fitChatMessage(prefix string, parts []string) string {
+	s := prefix
+	if len(s) > chatMessageMaxLength {
+		s = strings.ToValidUTF8(s[:chatMessageMaxLength], "")
+	}
+	for i, part := range parts {
+		next := strconv.Itoa(i+1) + ". " + part
+		if i > 0 {
+			next = " | " + next
+		}
+		if len(s)+len(next) > chatMessageMaxLength {
+			break
+		}
+		s += next
+	}
+	return s
+}
+
+func isStatsCommand(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "!topsongs", "!topartists", "!toprequesters", "!mystats":
+		return true
+	}
+	return false
+}
+
+// statsCommandReply answers !topsongs, !topartists, !toprequesters and !mystats, each takes an optional period
+func (a *App) statsCommandReply(event twitch.EventChannelChatMessage) string {
+	fields := strings.Fields(event.Message.Text)
+	command := strings.ToLower(fields[0])
+	period := ""
+	if len(fields) > 1 {
+		period = strings.ToLower(fields[1])
+	}
+
+	if command == "!mystats" {
+		stats, label, err := a.userStats(event.ChatterUserId, event.ChatterUserLogin, period)
+		if err != nil {
+			if err == errUnknownStatsPeriod {
+				return "Usage: !mystats [all|today|week|month|year]"
+			}
+			log.Println("Failed to get stats for", event.ChatterUserLogin, err)
+			return "Internal error when getting your stats"
+		}
+		if stats.Requests == 0 {
+			return "You have no song requests (" + label + ")."
+		}
+		s := "Your song requests (" + label + "): " + strconv.FormatInt(stats.Requests, 10) + " requested, " +
+			strconv.FormatInt(stats.Played, 10) + " played, " + strconv.FormatInt(stats.Skipped, 10) + " skipped."
+		if stats.TopSong.VideoID != "" {
+			s += " Most requested: " + stats.TopSong.SongTitle + " - " + stats.TopSong.ArtistName + " (" + strconv.FormatInt(stats.TopSong.Count, 10) + "x)"
+		}
+		return fitChatMessage(s, nil)
+	}
+
+	snapshot, err := a.statsSnapshot(period)
+	if err != nil {
+		if err == errUnknownStatsPeriod {
+			return "Usage: " + command + " [all|today|week|month|year]"
+		}
+		log.Println("Failed to get stats for", command, err)
+		return "Internal error when getting song request stats"
+	}
+	parts := []string{}
+	prefix := ""
+	switch command {
+	case "!topsongs":
+		prefix = "Top songs (" + snapshot.Label + "): "
+		for _, v := range snapshot.TopSongs {
+			parts = append(parts, v.SongTitle+" - "+v.ArtistName+" ("+strconv.FormatInt(v.Count, 10)+")")
+		}
+	case "!topartists":
+		prefix = "Top artists (" + snapshot.Label + "): "
+		for _, v := range snapshot.TopArtists {
+			parts = append(parts, v.ArtistName+" ("+strconv.FormatInt(v.Count, 10)+")")
+		}
+	case "!toprequesters":
+		prefix = "Top requesters (" + snapshot.Label + "): "
+		for _, v := range snapshot.TopRequesters {
+			parts = append(parts, v.TwitchUsername+" ("+strconv.FormatInt(v.Count, 10)+")")
+		}
+	}
+	if len(parts) == 0 {
+		return "No song requests yet (" + snapshot.Label + ")."
+	}
+	return fitChatMessage(prefix, parts)
+}
+
+func (a *App) replyStatsCommand(useProperHelix *helix.Client, properUserID string, event twitch.EventChannelChatMessage) {
+	useProperHelix.SendChatMessage(&helix.SendChatMessageParams{
+		BroadcasterID:        event.BroadcasterUserId,
+		SenderID:             properUserID,
+		Message:              a.statsCommandReply(event),
+		ReplyParentMessageID: event.MessageId,
+	})
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFitChatMessage(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name   string
+		prefix string
+		parts  []string
+		want   string
+	}{
+		{"no parts", "Top songs: ", nil, "Top songs: "},
+		{"numbered and joined", "Top: ", []string{"one", "two", "three"}, "Top: 1. one | 2. two | 3. three"},
+		{"stops before the part that does not fit", "Top: ", []string{long, long}, "Top: 1. " + long},
+		{"long prefix is cut", strings.Repeat("b", 600), []string{"one"}, strings.Repeat("b", chatMessageMaxLength)},
+		{"cut prefix stays valid utf8", strings.Repeat("b", chatMessageMaxLength-1) + "é", nil, strings.Repeat("b", chatMessageMaxLength-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fitChatMessage(tt.prefix, tt.parts)
+			if got != tt.want {
+				t.Errorf("fitChatMessage() = %q, want %q", got, tt.want)
+			}
+			if len(got) > chatMessageMaxLength {
+				t.Errorf("fitChatMessage() is %d bytes, over %d", len(got), chatMessageMaxLength)
+			}
+		})
+	}
+}
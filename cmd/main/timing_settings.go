@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+)
+
+var defaultTimingSettings = map[string]int{
+	data.DB_KEY_SONG_MIN_DURATION_SECONDS: 60,
+	data.DB_KEY_SONG_MAX_DURATION_SECONDS: 600,
+	data.DB_KEY_SKIP_COOLDOWN_SECONDS:     10,
+	data.DB_KEY_SONG_COOLDOWN_SECONDS:     10,
+	data.DB_KEY_QUEUE_COOLDOWN_SECONDS:    10,
+	data.DB_KEY_SONG_END_GUARD_SECONDS:    4,
+	data.DB_KEY_ROLE_CACHE_TTL_MINUTES:    120,
+	data.DB_KEY_SEARCH_CACHE_TTL_HOURS:    168,
+}
+
+func (a *App) timingSetting(key string) int {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	if v, ok := a.timingSettings[key]; ok {
+		return v
+	}
+	return defaultTimingSettings[key]
+}
+
+func (a *App) setTimingSetting(key string, value string) error {
+	if _, ok := defaultTimingSettings[key]; !ok {
+		return errors.New("unknown timing setting " + key)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New(key + " must be a positive number")
+	}
+	a.settingsMu.Lock()
+	a.timingSettings[key] = n
+	a.settingsMu.Unlock()
+	if key == data.DB_KEY_ROLE_CACHE_TTL_MINUTES {
+		ttl := time.Duration(n) * time.Minute
+		mainChannelFollowCache.SetTTL(ttl)
+		mainChannelUserRolesCache.SetTTL(ttl)
+	}
+	return nil
+}
+
+// songDurationBounds are the shortest and longest songs !sr accepts, in seconds
+func (a *App) songDurationBounds() (int, int) {
+	return a.timingSetting(data.DB_KEY_SONG_MIN_DURATION_SECONDS), a.timingSetting(data.DB_KEY_SONG_MAX_DURATION_SECONDS)
+}
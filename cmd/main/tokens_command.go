@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/store"
+)
+
+const tokensUsage = "usage: tokens reset"
+
+// runTokensCommand is `tokens reset`, run with the app closed.
+// It is the only way saved tokens get deleted when their key is lost, and it asks first
+func runTokensCommand(st *store.Store, args []string) error {
+	if len(args) != 1 || args[0] != "reset" {
+		return errors.New(tokensUsage)
+	}
+	fmt.Print("This deletes both saved Twitch logins and the token key check, type yes to continue: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(strings.ToLower(answer)) != "yes" {
+		return errors.New("nothing was deleted")
+	}
+	if err := st.ForgetTokenKey(context.Background()); err != nil {
+		return err
+	}
+	fmt.Println("Twitch tokens deleted, start the app and log in to Twitch again")
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	wsClientSendQueueSize = 64
+	wsClientWriteTimeout  = time.Second * 10
+	wsClientPingInterval  = time.Second * 30
+	// a client that sent nothing, not even a pong, for this long is gone
+	wsClientIdleTimeout = wsClientPingInterval * 3
+)
+
+// Browsers answer ping frames on their own, x/net/websocket swallows the pongs
+var wsPingCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+// heardConn notes when the peer last sent anything, x/net/websocket answers and drops
+// control frames itself so reads on the raw conn are the only way to see the pongs
+type heardConn struct {
+	net.Conn
+	lastHeard *atomic.Int64
+}
+
+func (c heardConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.lastHeard.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// heardResponseWriter hands the hijacked conn to x/net/websocket wrapped in a heardConn
+type heardResponseWriter struct {
+	http.ResponseWriter
+	lastHeard *atomic.Int64
+}
+
+func (w heardResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	heard := heardConn{Conn: conn, lastHeard: w.lastHeard}
+	var r io.Reader = heard
+	// keep whatever the http server read ahead of the handshake
+	if n := buf.Reader.Buffered(); n > 0 {
+		pending, _ := buf.Reader.Peek(n)
+		r = io.MultiReader(bytes.NewReader(bytes.Clone(pending)), heard)
+	}
+	return heard, bufio.NewReadWriter(bufio.NewReader(r), buf.Writer), nil
+}
+
+// wsClient owns all writes to one websocket, producers only ever queue messages
+type wsClient struct {
+	conn *websocket.Conn
+	// false for overlays without a session, they only get the public events and cannot send commands
+	authenticated bool
+	send          chan string
+	done          chan struct{}
+	closeOnce     sync.Once
+	// unix nanos of the last read from the peer, kept by heardConn
+	lastHeard *atomic.Int64
+}
+
+func newWsClient(conn *websocket.Conn, authenticated bool, lastHeard *atomic.Int64) *wsClient {
+	return &wsClient{
+		conn:          conn,
+		authenticated: authenticated,
+		lastHeard:     lastHeard,
+		send:          make(chan string, wsClientSendQueueSize),
+		done:          make(chan struct{}),
+	}
+}
+
+// queue never blocks, a client too slow to drain its queue gets disconnected
+func (c *wsClient) queue(msg string) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		c.close()
+		return false
+	}
+}
+
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *wsClient) writeLoop() {
+	ticker := time.NewTicker(wsClientPingInterval)
+	defer ticker.Stop()
+	// closing the conn also ends the read loop of the handler
+	defer c.conn.Close()
+	defer c.close()
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsClientWriteTimeout))
+			if err := websocket.Message.Send(c.conn, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			// a half open peer can keep taking writes, drop it once it stops answering pings
+			if time.Since(time.Unix(0, c.lastHeard.Load())) > wsClientIdleTimeout {
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(wsClientWriteTimeout))
+			if err := wsPingCodec.Send(c.conn, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// broadcast queues msg for every connected client without waiting on any of them
+func (a *App) broadcast(msg string) {
+	a.clientsMu.RLock()
+	defer a.clientsMu.RUnlock()
+	for c := range a.clients {
+		c.queue(msg)
+	}
+}
+
+// broadcastSplit queues msg for control panel sessions and publicMsg for overlays and other clients without a session
+func (a *App) broadcastSplit(msg string, publicMsg string) {
+	a.clientsMu.RLock()
+	defer a.clientsMu.RUnlock()
+	for c := range a.clients {
+		if c.authenticated {
+			c.queue(msg)
+		} else {
+			c.queue(publicMsg)
+		}
+	}
+}
+
+// broadcastAuthenticated is for account info and request moderation state
+func (a *App) broadcastAuthenticated(msg string) {
+	a.clientsMu.RLock()
+	defer a.clientsMu.RUnlock()
+	for c := range a.clients {
+		if c.authenticated {
+			c.queue(msg)
+		}
+	}
+}
@@ -3,6 +3,7 @@ import "./ConnectWithTwitchEntry.css";
 
 function ConnectWithTwitchEntry(props: { forBot: boolean }) {
 	const [params, setParams] = useState<URLSearchParams>();
+	const [error, setError] = useState("");
 
 	// on page load, set the oauth params
 	useEffect(() => {
@@ -21,6 +22,7 @@ function ConnectWithTwitchEntry(props: { forBot: boolean }) {
 			"user:write:chat",
 			"user:bot",
 			"channel:bot",
+			"moderator:manage:announcements",
 		];
 		if (!props.forBot) {
 			scopes.push(
@@ -28,19 +30,40 @@ function ConnectWithTwitchEntry(props: { forBot: boolean }) {
 				"channel:read:vips",
 				"moderation:read",
 				"channel:read:subscriptions",
+				"moderator:read:followers",
+				"bits:read",
 			);
 		}
 		params.append("scope", scopes.join(" "));
-		if (props.forBot) {
-			params.append("state", "bot");
-		}
-		setParams(params);
+		// the server hands out a single use state and remembers which account it is for
+		fetch("/api/v1/oauth-state?bot=" + (props.forBot ? "true" : "false"), {
+			method: "POST",
+		})
+			.then((response) => response.json())
+			.then((j: { state?: string; error?: string }) => {
+				if (!j.state) {
+					setError(j.error ?? "Failed to start Twitch connection");
+					return;
+				}
+				params.append("state", j.state);
+				setParams(params);
+			})
+			.catch((e) => {
+				setError("Failed to start Twitch connection " + e);
+			});
 		/*
 			example fragment: #access_token=73d0f8mkabpbmjp921asv2jaidwxn&scope=channel%3Amanage%3Apolls+channel%3Aread%3Apolls&state=c3ab8aa609ea11e793ae92361f002671&token_type=bearer
 			example error: ?error=redirect_mismatch&error_description=Parameter+redirect_uri+does+not+match+registered+URI
 		*/
 	}, []);
 
+	if (error) {
+		return <h3>{error}</h3>;
+	}
+	if (!params) {
+		return <></>;
+	}
+
 	return (
 		<>
 			<a href={`https://id.twitch.tv/oauth2/authorize?${params}`}>
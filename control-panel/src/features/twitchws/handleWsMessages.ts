@@ -9,6 +9,10 @@ export const handleWsMessages = (
 
 	const d: MsgTwitchInfo = JSON.parse(data);
 	console.log(d);
+	// NOW_PLAYING, QUEUE_UPDATED and the other events are not shown here yet
+	if (d.type !== "TWITCH_INFO") {
+		return;
+	}
 	dispatch(
 		setTwitchInfo({
 			expires_in: d.expiry_date,
@@ -13,7 +13,8 @@ export interface ITwitchState {
 
 const initialState: ITwitchState = {
 	expires_in: "",
-	hostname: "127.0.0.1:3999",
+	// same host as the page so the session cookie is sent along
+	hostname: import.meta.env.DEV ? "127.0.0.1:3999" : window.location.host,
 	twitch_song_request_reward_id: "",
 	login: "",
 	login_bot: "",
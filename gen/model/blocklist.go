@@ -0,0 +1,15 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+type Blocklist struct {
+	Kind      string `sql:"primary_key"`
+	Value     string `sql:"primary_key"`
+	Label     string
+	CreatedAt string
+}
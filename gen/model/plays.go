@@ -0,0 +1,23 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+type Plays struct {
+	ID              *int32 `sql:"primary_key"`
+	VideoID         string
+	SongTitle       string
+	ArtistName      string
+	ImageURL        string
+	DurationSeconds int32
+	StartedAt       string
+	EndedAt         string
+	PlayedSeconds   int32
+	Skipped         bool
+	SkippedBy       string
+	RequestID       *int32
+}
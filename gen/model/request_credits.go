@@ -0,0 +1,14 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+type RequestCredits struct {
+	TwitchUserID   string `sql:"primary_key"`
+	TwitchUsername string
+	Credits        int32
+}
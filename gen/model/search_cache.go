@@ -0,0 +1,21 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+type SearchCache struct {
+	Kind            string `sql:"primary_key"`
+	Lookup          string `sql:"primary_key"`
+	VideoID         string
+	SongTitle       string
+	ArtistName      string
+	ImageURL        string
+	RawTimeData     string
+	DurationSeconds int32
+	VideoType       string
+	CachedAt        string
+}
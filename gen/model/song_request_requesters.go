@@ -8,7 +8,13 @@
 package model
 
 type SongRequestRequesters struct {
+	ID             *int32 `sql:"primary_key"`
 	VideoID        string
+	TwitchUserID   string
 	TwitchUsername string
 	RequestedAt    string
+	Source         string
+	Outcome        string
+	OutcomeReason  string
+	OutcomeAt      string
 }
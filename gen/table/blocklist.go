@@ -0,0 +1,87 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var Blocklist = newBlocklistTable("", "blocklist", "")
+
+type blocklistTable struct {
+	sqlite.Table
+
+	// Columns
+	Kind      sqlite.ColumnString
+	Value     sqlite.ColumnString
+	Label     sqlite.ColumnString
+	CreatedAt sqlite.ColumnString
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+	DefaultColumns sqlite.ColumnList
+}
+
+type BlocklistTable struct {
+	blocklistTable
+
+	EXCLUDED blocklistTable
+}
+
+// AS creates new BlocklistTable with assigned alias
+func (a BlocklistTable) AS(alias string) *BlocklistTable {
+	return newBlocklistTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new BlocklistTable with assigned schema name
+func (a BlocklistTable) FromSchema(schemaName string) *BlocklistTable {
+	return newBlocklistTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new BlocklistTable with assigned table prefix
+func (a BlocklistTable) WithPrefix(prefix string) *BlocklistTable {
+	return newBlocklistTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new BlocklistTable with assigned table suffix
+func (a BlocklistTable) WithSuffix(suffix string) *BlocklistTable {
+	return newBlocklistTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newBlocklistTable(schemaName, tableName, alias string) *BlocklistTable {
+	return &BlocklistTable{
+		blocklistTable: newBlocklistTableImpl(schemaName, tableName, alias),
+		EXCLUDED:       newBlocklistTableImpl("", "excluded", ""),
+	}
+}
+
+func newBlocklistTableImpl(schemaName, tableName, alias string) blocklistTable {
+	var (
+		KindColumn      = sqlite.StringColumn("kind")
+		ValueColumn     = sqlite.StringColumn("value")
+		LabelColumn     = sqlite.StringColumn("label")
+		CreatedAtColumn = sqlite.StringColumn("created_at")
+		allColumns      = sqlite.ColumnList{KindColumn, ValueColumn, LabelColumn, CreatedAtColumn}
+		mutableColumns  = sqlite.ColumnList{LabelColumn, CreatedAtColumn}
+		defaultColumns  = sqlite.ColumnList{}
+	)
+
+	return blocklistTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		Kind:      KindColumn,
+		Value:     ValueColumn,
+		Label:     LabelColumn,
+		CreatedAt: CreatedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+		DefaultColumns: defaultColumns,
+	}
+}
@@ -0,0 +1,111 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var Plays = newPlaysTable("", "plays", "")
+
+type playsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID              sqlite.ColumnInteger
+	VideoID         sqlite.ColumnString
+	SongTitle       sqlite.ColumnString
+	ArtistName      sqlite.ColumnString
+	ImageURL        sqlite.ColumnString
+	DurationSeconds sqlite.ColumnInteger
+	StartedAt       sqlite.ColumnString
+	EndedAt         sqlite.ColumnString
+	PlayedSeconds   sqlite.ColumnInteger
+	Skipped         sqlite.ColumnBool
+	SkippedBy       sqlite.ColumnString
+	RequestID       sqlite.ColumnInteger
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+	DefaultColumns sqlite.ColumnList
+}
+
+type PlaysTable struct {
+	playsTable
+
+	EXCLUDED playsTable
+}
+
+// AS creates new PlaysTable with assigned alias
+func (a PlaysTable) AS(alias string) *PlaysTable {
+	return newPlaysTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new PlaysTable with assigned schema name
+func (a PlaysTable) FromSchema(schemaName string) *PlaysTable {
+	return newPlaysTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new PlaysTable with assigned table prefix
+func (a PlaysTable) WithPrefix(prefix string) *PlaysTable {
+	return newPlaysTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new PlaysTable with assigned table suffix
+func (a PlaysTable) WithSuffix(suffix string) *PlaysTable {
+	return newPlaysTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newPlaysTable(schemaName, tableName, alias string) *PlaysTable {
+	return &PlaysTable{
+		playsTable: newPlaysTableImpl(schemaName, tableName, alias),
+		EXCLUDED:   newPlaysTableImpl("", "excluded", ""),
+	}
+}
+
+func newPlaysTableImpl(schemaName, tableName, alias string) playsTable {
+	var (
+		IDColumn              = sqlite.IntegerColumn("id")
+		VideoIDColumn         = sqlite.StringColumn("video_id")
+		SongTitleColumn       = sqlite.StringColumn("song_title")
+		ArtistNameColumn      = sqlite.StringColumn("artist_name")
+		ImageURLColumn        = sqlite.StringColumn("image_url")
+		DurationSecondsColumn = sqlite.IntegerColumn("duration_seconds")
+		StartedAtColumn       = sqlite.StringColumn("started_at")
+		EndedAtColumn         = sqlite.StringColumn("ended_at")
+		PlayedSecondsColumn   = sqlite.IntegerColumn("played_seconds")
+		SkippedColumn         = sqlite.BoolColumn("skipped")
+		SkippedByColumn       = sqlite.StringColumn("skipped_by")
+		RequestIDColumn       = sqlite.IntegerColumn("request_id")
+		allColumns            = sqlite.ColumnList{IDColumn, VideoIDColumn, SongTitleColumn, ArtistNameColumn, ImageURLColumn, DurationSecondsColumn, StartedAtColumn, EndedAtColumn, PlayedSecondsColumn, SkippedColumn, SkippedByColumn, RequestIDColumn}
+		mutableColumns        = sqlite.ColumnList{VideoIDColumn, SongTitleColumn, ArtistNameColumn, ImageURLColumn, DurationSecondsColumn, StartedAtColumn, EndedAtColumn, PlayedSecondsColumn, SkippedColumn, SkippedByColumn, RequestIDColumn}
+		defaultColumns        = sqlite.ColumnList{ImageURLColumn, DurationSecondsColumn, EndedAtColumn, PlayedSecondsColumn, SkippedColumn, SkippedByColumn}
+	)
+
+	return playsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:              IDColumn,
+		VideoID:         VideoIDColumn,
+		SongTitle:       SongTitleColumn,
+		ArtistName:      ArtistNameColumn,
+		ImageURL:        ImageURLColumn,
+		DurationSeconds: DurationSecondsColumn,
+		StartedAt:       StartedAtColumn,
+		EndedAt:         EndedAtColumn,
+		PlayedSeconds:   PlayedSecondsColumn,
+		Skipped:         SkippedColumn,
+		SkippedBy:       SkippedByColumn,
+		RequestID:       RequestIDColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+		DefaultColumns: defaultColumns,
+	}
+}
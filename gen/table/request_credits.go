@@ -0,0 +1,84 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var RequestCredits = newRequestCreditsTable("", "request_credits", "")
+
+type requestCreditsTable struct {
+	sqlite.Table
+
+	// Columns
+	TwitchUserID   sqlite.ColumnString
+	TwitchUsername sqlite.ColumnString
+	Credits        sqlite.ColumnInteger
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+	DefaultColumns sqlite.ColumnList
+}
+
+type RequestCreditsTable struct {
+	requestCreditsTable
+
+	EXCLUDED requestCreditsTable
+}
+
+// AS creates new RequestCreditsTable with assigned alias
+func (a RequestCreditsTable) AS(alias string) *RequestCreditsTable {
+	return newRequestCreditsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new RequestCreditsTable with assigned schema name
+func (a RequestCreditsTable) FromSchema(schemaName string) *RequestCreditsTable {
+	return newRequestCreditsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new RequestCreditsTable with assigned table prefix
+func (a RequestCreditsTable) WithPrefix(prefix string) *RequestCreditsTable {
+	return newRequestCreditsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new RequestCreditsTable with assigned table suffix
+func (a RequestCreditsTable) WithSuffix(suffix string) *RequestCreditsTable {
+	return newRequestCreditsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newRequestCreditsTable(schemaName, tableName, alias string) *RequestCreditsTable {
+	return &RequestCreditsTable{
+		requestCreditsTable: newRequestCreditsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:            newRequestCreditsTableImpl("", "excluded", ""),
+	}
+}
+
+func newRequestCreditsTableImpl(schemaName, tableName, alias string) requestCreditsTable {
+	var (
+		TwitchUserIDColumn   = sqlite.StringColumn("twitch_user_id")
+		TwitchUsernameColumn = sqlite.StringColumn("twitch_username")
+		CreditsColumn        = sqlite.IntegerColumn("credits")
+		allColumns           = sqlite.ColumnList{TwitchUserIDColumn, TwitchUsernameColumn, CreditsColumn}
+		mutableColumns       = sqlite.ColumnList{TwitchUsernameColumn, CreditsColumn}
+		defaultColumns       = sqlite.ColumnList{CreditsColumn}
+	)
+
+	return requestCreditsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		TwitchUserID:   TwitchUserIDColumn,
+		TwitchUsername: TwitchUsernameColumn,
+		Credits:        CreditsColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+		DefaultColumns: defaultColumns,
+	}
+}
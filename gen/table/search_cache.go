@@ -0,0 +1,105 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var SearchCache = newSearchCacheTable("", "search_cache", "")
+
+type searchCacheTable struct {
+	sqlite.Table
+
+	// Columns
+	Kind            sqlite.ColumnString
+	Lookup          sqlite.ColumnString
+	VideoID         sqlite.ColumnString
+	SongTitle       sqlite.ColumnString
+	ArtistName      sqlite.ColumnString
+	ImageURL        sqlite.ColumnString
+	RawTimeData     sqlite.ColumnString
+	DurationSeconds sqlite.ColumnInteger
+	VideoType       sqlite.ColumnString
+	CachedAt        sqlite.ColumnString
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+	DefaultColumns sqlite.ColumnList
+}
+
+type SearchCacheTable struct {
+	searchCacheTable
+
+	EXCLUDED searchCacheTable
+}
+
+// AS creates new SearchCacheTable with assigned alias
+func (a SearchCacheTable) AS(alias string) *SearchCacheTable {
+	return newSearchCacheTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new SearchCacheTable with assigned schema name
+func (a SearchCacheTable) FromSchema(schemaName string) *SearchCacheTable {
+	return newSearchCacheTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new SearchCacheTable with assigned table prefix
+func (a SearchCacheTable) WithPrefix(prefix string) *SearchCacheTable {
+	return newSearchCacheTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new SearchCacheTable with assigned table suffix
+func (a SearchCacheTable) WithSuffix(suffix string) *SearchCacheTable {
+	return newSearchCacheTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newSearchCacheTable(schemaName, tableName, alias string) *SearchCacheTable {
+	return &SearchCacheTable{
+		searchCacheTable: newSearchCacheTableImpl(schemaName, tableName, alias),
+		EXCLUDED:         newSearchCacheTableImpl("", "excluded", ""),
+	}
+}
+
+func newSearchCacheTableImpl(schemaName, tableName, alias string) searchCacheTable {
+	var (
+		KindColumn            = sqlite.StringColumn("kind")
+		LookupColumn          = sqlite.StringColumn("lookup")
+		VideoIDColumn         = sqlite.StringColumn("video_id")
+		SongTitleColumn       = sqlite.StringColumn("song_title")
+		ArtistNameColumn      = sqlite.StringColumn("artist_name")
+		ImageURLColumn        = sqlite.StringColumn("image_url")
+		RawTimeDataColumn     = sqlite.StringColumn("raw_time_data")
+		DurationSecondsColumn = sqlite.IntegerColumn("duration_seconds")
+		VideoTypeColumn       = sqlite.StringColumn("video_type")
+		CachedAtColumn        = sqlite.StringColumn("cached_at")
+		allColumns            = sqlite.ColumnList{KindColumn, LookupColumn, VideoIDColumn, SongTitleColumn, ArtistNameColumn, ImageURLColumn, RawTimeDataColumn, DurationSecondsColumn, VideoTypeColumn, CachedAtColumn}
+		mutableColumns        = sqlite.ColumnList{VideoIDColumn, SongTitleColumn, ArtistNameColumn, ImageURLColumn, RawTimeDataColumn, DurationSecondsColumn, VideoTypeColumn, CachedAtColumn}
+		defaultColumns        = sqlite.ColumnList{ImageURLColumn, RawTimeDataColumn, DurationSecondsColumn, VideoTypeColumn}
+	)
+
+	return searchCacheTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		Kind:            KindColumn,
+		Lookup:          LookupColumn,
+		VideoID:         VideoIDColumn,
+		SongTitle:       SongTitleColumn,
+		ArtistName:      ArtistNameColumn,
+		ImageURL:        ImageURLColumn,
+		RawTimeData:     RawTimeDataColumn,
+		DurationSeconds: DurationSecondsColumn,
+		VideoType:       VideoTypeColumn,
+		CachedAt:        CachedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+		DefaultColumns: defaultColumns,
+	}
+}
@@ -17,9 +17,15 @@ type songRequestRequestersTable struct {
 	sqlite.Table
 
 	// Columns
+	ID             sqlite.ColumnInteger
 	VideoID        sqlite.ColumnString
+	TwitchUserID   sqlite.ColumnString
 	TwitchUsername sqlite.ColumnString
 	RequestedAt    sqlite.ColumnString
+	Source         sqlite.ColumnString
+	Outcome        sqlite.ColumnString
+	OutcomeReason  sqlite.ColumnString
+	OutcomeAt      sqlite.ColumnString
 
 	AllColumns     sqlite.ColumnList
 	MutableColumns sqlite.ColumnList
@@ -61,21 +67,33 @@ func newSongRequestRequestersTable(schemaName, tableName, alias string) *SongReq
 
 func newSongRequestRequestersTableImpl(schemaName, tableName, alias string) songRequestRequestersTable {
 	var (
+		IDColumn             = sqlite.IntegerColumn("id")
 		VideoIDColumn        = sqlite.StringColumn("video_id")
+		TwitchUserIDColumn   = sqlite.StringColumn("twitch_user_id")
 		TwitchUsernameColumn = sqlite.StringColumn("twitch_username")
 		RequestedAtColumn    = sqlite.StringColumn("requested_at")
-		allColumns           = sqlite.ColumnList{VideoIDColumn, TwitchUsernameColumn, RequestedAtColumn}
-		mutableColumns       = sqlite.ColumnList{VideoIDColumn, TwitchUsernameColumn, RequestedAtColumn}
-		defaultColumns       = sqlite.ColumnList{RequestedAtColumn}
+		SourceColumn         = sqlite.StringColumn("source")
+		OutcomeColumn        = sqlite.StringColumn("outcome")
+		OutcomeReasonColumn  = sqlite.StringColumn("outcome_reason")
+		OutcomeAtColumn      = sqlite.StringColumn("outcome_at")
+		allColumns           = sqlite.ColumnList{IDColumn, VideoIDColumn, TwitchUserIDColumn, TwitchUsernameColumn, RequestedAtColumn, SourceColumn, OutcomeColumn, OutcomeReasonColumn, OutcomeAtColumn}
+		mutableColumns       = sqlite.ColumnList{VideoIDColumn, TwitchUserIDColumn, TwitchUsernameColumn, RequestedAtColumn, SourceColumn, OutcomeColumn, OutcomeReasonColumn, OutcomeAtColumn}
+		defaultColumns       = sqlite.ColumnList{TwitchUserIDColumn, SourceColumn, OutcomeColumn, OutcomeReasonColumn, OutcomeAtColumn}
 	)
 
 	return songRequestRequestersTable{
 		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
+		ID:             IDColumn,
 		VideoID:        VideoIDColumn,
+		TwitchUserID:   TwitchUserIDColumn,
 		TwitchUsername: TwitchUsernameColumn,
 		RequestedAt:    RequestedAtColumn,
+		Source:         SourceColumn,
+		Outcome:        OutcomeColumn,
+		OutcomeReason:  OutcomeReasonColumn,
+		OutcomeAt:      OutcomeAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -10,6 +10,10 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	Blocklist = Blocklist.FromSchema(schema)
+	Plays = Plays.FromSchema(schema)
+	RequestCredits = RequestCredits.FromSchema(schema)
+	SearchCache = SearchCache.FromSchema(schema)
 	Settings = Settings.FromSchema(schema)
 	SongRequestRequesters = SongRequestRequesters.FromSchema(schema)
 	SongRequests = SongRequests.FromSchema(schema)
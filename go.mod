@@ -11,6 +11,7 @@ require (
 	github.com/recws-org/recws v1.4.0
 	github.com/valyala/fastjson v1.6.4
 	golang.org/x/net v0.47.0
+	golang.org/x/time v0.14.0
 )
 
 require (
@@ -33,7 +34,6 @@ require (
 	golang.org/x/crypto v0.45.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
-	golang.org/x/time v0.14.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -46,6 +46,12 @@ func (s *TwitchWS) StartCtx(ctx context.Context) error {
 			if event == twitch.SubChannelChatMessage {
 				condition["user_id"] = *s.mainUserId
 			}
+			if event == twitch.SubChannelRaid {
+				// raids are only filterable by the raided channel
+				condition = map[string]string{
+					"to_broadcaster_user_id": *s.mainUserId,
+				}
+			}
 
 			_, err := twitch.SubscribeEvent(twitch.SubscribeRequest{
 				SessionID:   message.Payload.Session.ID,
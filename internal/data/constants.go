@@ -12,5 +12,48 @@ const (
 	DB_KEY_TWITCH_ACCESS_TOKEN           = "twitch_access_token"
 	DB_KEY_TWITCH_ACCESS_TOKEN_BOT       = "twitch_access_token_bot"
 	DB_KEY_TWITCH_SONG_REQUEST_REWARD_ID = "twitch_song_request_reward_id"
+	DB_KEY_PERMISSION_SR                 = "permission_sr"
+	DB_KEY_PERMISSION_SR_REWARD          = "permission_sr_reward"
+	DB_KEY_PERMISSION_SKIP               = "permission_skip"
+	DB_KEY_PERMISSION_SONG               = "permission_song"
+	DB_KEY_PERMISSION_QUEUE              = "permission_queue"
+	DB_KEY_PERMISSION_ANNOUNCE           = "permission_announce"
+	DB_KEY_PERMISSION_STATS              = "permission_stats"
+	DB_KEY_PERMISSION_BLOCKLIST          = "permission_blocklist"
+	DB_KEY_FOLLOWER_MIN_AGE_MINUTES      = "follower_min_age_minutes"
+	DB_KEY_SHARED_CHAT_REQUESTS          = "shared_chat_requests"
+	DB_KEY_PERMISSION_SHARED_CHAT        = "permission_shared_chat"
+	DB_KEY_CREDITS_CHEER_BITS            = "credits_cheer_bits"
+	DB_KEY_CREDITS_PER_SUB               = "credits_per_sub"
+	DB_KEY_CREDITS_PER_GIFTED_SUB        = "credits_per_gifted_sub"
+	DB_KEY_CREDITS_PER_RESUB             = "credits_per_resub"
+	DB_KEY_CREDITS_PER_RAID              = "credits_per_raid"
+	DB_KEY_NOW_PLAYING_ENABLED           = "now_playing_enabled"
+	DB_KEY_NOW_PLAYING_MODE              = "now_playing_mode"
+	DB_KEY_NOW_PLAYING_METHOD            = "now_playing_method"
+	DB_KEY_NOW_PLAYING_TEMPLATE          = "now_playing_template"
+	DB_KEY_NOW_PLAYING_TEMPLATE_FILLER   = "now_playing_template_filler"
+	DB_KEY_REQUESTS_REQUIRE_APPROVAL     = "requests_require_approval"
+	DB_KEY_FILE_OUTPUT_DIR               = "file_output_dir"
+	DB_KEY_FILE_OUTPUT_NOW_PLAYING       = "file_output_now_playing_format"
+	DB_KEY_FILE_OUTPUT_REQUESTER         = "file_output_requester_format"
+	DB_KEY_FILE_OUTPUT_QUEUE             = "file_output_queue_format"
+	DB_KEY_FILE_OUTPUT_QUEUE_COUNT       = "file_output_queue_count"
+	DB_KEY_SONG_MIN_DURATION_SECONDS     = "song_min_duration_seconds"
+	DB_KEY_SONG_MAX_DURATION_SECONDS     = "song_max_duration_seconds"
+	DB_KEY_SKIP_COOLDOWN_SECONDS         = "skip_cooldown_seconds"
+	DB_KEY_SONG_COOLDOWN_SECONDS         = "song_cooldown_seconds"
+	DB_KEY_QUEUE_COOLDOWN_SECONDS        = "queue_cooldown_seconds"
+	DB_KEY_SONG_END_GUARD_SECONDS        = "song_end_guard_seconds"
+	DB_KEY_ROLE_CACHE_TTL_MINUTES        = "role_cache_ttl_minutes"
+	DB_KEY_SEARCH_CACHE_TTL_HOURS        = "search_cache_ttl_hours"
+	DB_KEY_PUBLIC_QUEUE_ENABLED          = "public_queue_enabled"
+	DB_KEY_PUBLIC_QUEUE_ADDRESS          = "public_queue_address"
+	DB_KEY_PUBLIC_QUEUE_BASE_URL         = "public_queue_base_url"
+	DB_KEY_PUBLIC_QUEUE_RATE_LIMIT       = "public_queue_rate_limit"
+	DB_KEY_SESSION_SECRET                = "session_secret"
+	DB_KEY_TOKEN_KEY_SALT                = "token_key_salt"
+	DB_KEY_TOKEN_KEY_CHECK               = "token_key_check"
+	DB_KEY_OVERLAY_PRESET_PREFIX         = "overlay_preset_"
 	TWITCH_SERVER_DATE_LAYOUT            = time.RFC1123
 )
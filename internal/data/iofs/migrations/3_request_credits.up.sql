@@ -0,0 +1,5 @@
+CREATE TABLE request_credits (
+    twitch_user_id TEXT PRIMARY KEY,
+    twitch_username TEXT NOT NULL,
+    credits INTEGER NOT NULL DEFAULT 0
+) WITHOUT ROWID;
@@ -0,0 +1,5 @@
+CREATE TABLE banned_users (
+    twitch_user_id TEXT PRIMARY KEY,
+    twitch_username TEXT NOT NULL,
+    created_at TEXT NOT NULL
+) WITHOUT ROWID;
@@ -0,0 +1,12 @@
+CREATE TABLE song_request_requesters_old (
+    video_id TEXT NOT NULL,
+    twitch_username TEXT NOT NULL,
+    requested_at TEXT NOT NULL DEFAULT datetime,
+    FOREIGN KEY(video_id) REFERENCES song_requests(video_id)
+);
+
+INSERT INTO song_request_requesters_old (rowid, video_id, twitch_username, requested_at)
+SELECT id, video_id, twitch_username, requested_at FROM song_request_requesters;
+
+DROP TABLE song_request_requesters;
+ALTER TABLE song_request_requesters_old RENAME TO song_request_requesters;
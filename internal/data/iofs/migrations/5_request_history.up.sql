@@ -0,0 +1,35 @@
+CREATE TABLE song_request_requesters_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id TEXT NOT NULL,
+    twitch_user_id TEXT NOT NULL DEFAULT '',
+    twitch_username TEXT NOT NULL,
+    requested_at TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL DEFAULT 'queued',
+    outcome_reason TEXT NOT NULL DEFAULT '',
+    outcome_at TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(video_id) REFERENCES song_requests(video_id)
+);
+
+-- requested_at was RFC1123 in local time, like Mon, 02 Jan 2006 15:04:05 CET
+INSERT INTO song_request_requesters_new (id, video_id, twitch_username, requested_at, outcome)
+SELECT
+    rowid,
+    video_id,
+    twitch_username,
+    COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', printf('%s-%02d-%s %s',
+        substr(requested_at, 13, 4),
+        (instr('JanFebMarAprMayJunJulAugSepOctNovDec', substr(requested_at, 9, 3)) + 2) / 3,
+        substr(requested_at, 6, 2),
+        substr(requested_at, 18, 8)
+    ), 'utc'), requested_at),
+    ''
+FROM song_request_requesters;
+
+DROP TABLE song_request_requesters;
+ALTER TABLE song_request_requesters_new RENAME TO song_request_requesters;
+
+CREATE INDEX song_request_requesters_video_id ON song_request_requesters (video_id);
+CREATE INDEX song_request_requesters_requested_at ON song_request_requesters (requested_at);
+CREATE INDEX song_request_requesters_twitch_user_id ON song_request_requesters (twitch_user_id);
+CREATE INDEX song_request_requesters_twitch_username ON song_request_requesters (lower(twitch_username));
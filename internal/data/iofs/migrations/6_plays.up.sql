@@ -0,0 +1,19 @@
+CREATE TABLE plays (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id TEXT NOT NULL,
+    song_title TEXT NOT NULL,
+    artist_name TEXT NOT NULL,
+    image_url TEXT NOT NULL DEFAULT '',
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    started_at TEXT NOT NULL,
+    ended_at TEXT NOT NULL DEFAULT '',
+    played_seconds INTEGER NOT NULL DEFAULT 0,
+    skipped BOOLEAN NOT NULL DEFAULT FALSE,
+    skipped_by TEXT NOT NULL DEFAULT '',
+    request_id INTEGER,
+    FOREIGN KEY(request_id) REFERENCES song_request_requesters(id)
+);
+
+CREATE INDEX plays_started_at ON plays (started_at);
+CREATE INDEX plays_video_id ON plays (video_id);
+CREATE INDEX plays_request_id ON plays (request_id);
@@ -0,0 +1,15 @@
+CREATE TABLE search_cache (
+    kind TEXT NOT NULL,
+    lookup TEXT NOT NULL,
+    video_id TEXT NOT NULL,
+    song_title TEXT NOT NULL,
+    artist_name TEXT NOT NULL,
+    image_url TEXT NOT NULL DEFAULT '',
+    raw_time_data TEXT NOT NULL DEFAULT '',
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    video_type TEXT NOT NULL DEFAULT '',
+    cached_at TEXT NOT NULL,
+    PRIMARY KEY (kind, lookup)
+);
+
+CREATE INDEX search_cache_video_id ON search_cache (video_id);
@@ -0,0 +1,8 @@
+CREATE TABLE banned_users (
+    twitch_user_id TEXT PRIMARY KEY,
+    twitch_username TEXT NOT NULL,
+    created_at TEXT NOT NULL
+) WITHOUT ROWID;
+INSERT INTO banned_users (twitch_user_id, twitch_username, created_at)
+SELECT value, label, created_at FROM blocklist WHERE kind = 'user';
+DROP TABLE IF EXISTS blocklist;
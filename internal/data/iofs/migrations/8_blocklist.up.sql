@@ -0,0 +1,10 @@
+CREATE TABLE blocklist (
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    label TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (kind, value)
+) WITHOUT ROWID;
+INSERT INTO blocklist (kind, value, label, created_at)
+SELECT 'user', twitch_user_id, twitch_username, created_at FROM banned_users;
+DROP TABLE banned_users;
@@ -0,0 +1,24 @@
+package data
+
+var secretSettingKeys = map[string]struct{}{
+	DB_KEY_TWITCH_ACCESS_TOKEN:     {},
+	DB_KEY_TWITCH_ACCESS_TOKEN_BOT: {},
+	DB_KEY_SESSION_SECRET:          {},
+}
+
+// IsSecretSetting is true for settings that give access to the Twitch accounts or the control panel
+func IsSecretSetting(key string) bool {
+	_, ok := secretSettingKeys[key]
+	return ok
+}
+
+// IsEncryptedSetting is true for the settings kept encrypted in the database
+func IsEncryptedSetting(key string) bool {
+	return key == DB_KEY_TWITCH_ACCESS_TOKEN || key == DB_KEY_TWITCH_ACCESS_TOKEN_BOT
+}
+
+// IsTokenKeySetting is true for the salt and check value of the token key,
+// they belong to one database and its key and are never exported or imported
+func IsTokenKeySetting(key string) bool {
+	return key == DB_KEY_TOKEN_KEY_SALT || key == DB_KEY_TOKEN_KEY_CHECK
+}
@@ -0,0 +1,82 @@
+package databaseconn
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupsDir sits next to the database in the data directory
+const backupsDir = "backups"
+
+const (
+	BackupReasonMigrate = "pre-migrate"
+	BackupReasonManual  = "manual"
+	BackupReasonRestore = "pre-restore"
+)
+
+// BackupsDir is where Backup writes its copies of the database at path
+func BackupsDir(path string) string {
+	return filepath.Join(filepath.Dir(path), backupsDir)
+}
+
+// Backup writes a consistent copy of the database at path into BackupsDir and returns the copy's path,
+// reason ends up in the file name
+func Backup(path string, reason string) (string, error) {
+	dir := BackupsDir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(dbName, filepath.Ext(dbName)) + "-" + time.Now().Format("20060102-150405") + "-" + reason + ".db"
+	backupPath := filepath.Join(dir, name)
+	if _, err := os.Stat(backupPath); err == nil {
+		return "", errors.New(backupPath + " already exists")
+	}
+
+	db, err := open(path, migrateDSNOptions)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	// VACUUM INTO includes whatever is still in the WAL and works while the app has the database open
+	if _, err := db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		os.Remove(backupPath)
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// ListBackups returns the copies in BackupsDir, newest first
+func ListBackups(path string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(BackupsDir(path), strings.TrimSuffix(dbName, filepath.Ext(dbName))+"-*.db"))
+	if err != nil {
+		return nil, err
+	}
+	// the timestamp in the name sorts in time order
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+// Restore replaces the database at path with the backup, nothing may have the database open
+func Restore(path string, backupPath string) error {
+	if _, err := os.Stat(backupPath); err != nil {
+		return err
+	}
+	tmp := path + ".restoring"
+	os.Remove(tmp)
+	if err := copyFile(backupPath, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// the WAL belongs to the database being replaced and would be replayed over the backup
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
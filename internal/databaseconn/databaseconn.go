@@ -2,109 +2,272 @@ package databaseconn
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
 const dbName = "pear-desktop-twitch-song-requests.db"
 
-func init() {
-	// create db if missing
-	if _, err := os.Stat(dbName); os.IsNotExist(err) {
-		// file does not exist
-		file, err := os.Create(dbName)
-		if err != nil {
-			log.Fatal(err.Error())
+// Path is where the database lives inside the data directory
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, dbName)
+}
+
+// Create makes an empty database file at path if there is none yet
+func Create(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// AdoptLegacy moves a database left in the working directory by older versions to path,
+// it does nothing when path already has a database
+func AdoptLegacy(path string) (bool, error) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return false, err
+	}
+	legacy, err := filepath.Abs(dbName)
+	if err != nil || legacy == path {
+		return false, err
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return false, nil
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if _, err := os.Stat(legacy + suffix); err != nil {
+			continue
+		}
+		if err := moveFile(legacy+suffix, path+suffix); err != nil {
+			return false, err
 		}
-		file.Close()
 	}
+	return true, nil
+}
+
+// moveFile falls back to copying when rename cannot cross drives, the original is then kept
+func moveFile(from string, to string) error {
+	if os.Rename(from, to) == nil {
+		return nil
+	}
+	return copyFile(from, to)
+}
+
+// copyFile fails when to exists
+func copyFile(from string, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	return dst.Close()
+}
+
+// dsnOptions are applied to every connection in the pool, WAL lets readers run while a write is in progress.
+// Migrations run without foreign keys so tables can be rebuilt
+const (
+	dsnOptions        = "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on"
+	migrateDSNOptions = "?_journal_mode=WAL&_busy_timeout=5000"
+)
+
+// Open returns a pooled handle that is meant to be opened once and shared for the life of the app
+func Open(path string) (*sql.DB, error) {
+	return open(path, dsnOptions)
 }
 
-func NewDBConnection() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "file:"+dbName)
+func open(path string, options string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+options)
 	if err != nil {
 		return nil, err
 	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenMemory returns a fresh migrated in-memory database, used by tests
+func OpenMemory() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	if err != nil {
+		return nil, err
+	}
+	// every connection to :memory: is its own database, keep the one
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	m, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	// closing the migrator would close db too
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
-func getMigrator() (*migrate.Migrate, error) {
-	var m *migrate.Migrate
-	var err error
+// newMigrator takes over db, closing the migrator closes db
+func newMigrator(db *sql.DB) (*migrate.Migrate, error) {
 	d, err := iofs.New(data.GetMigrationFS(), "iofs/migrations")
 	if err != nil {
 		return nil, err
 	}
-	m, err = migrate.NewWithSourceInstance("iofs", d, "sqlite3://"+dbName)
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithInstance("iofs", d, "sqlite3", driver)
+}
+
+func getMigrator(path string) (*migrate.Migrate, error) {
+	db, err := open(path, migrateDSNOptions)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMigrator(db)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 	return m, nil
 }
 
-func Migrate() error {
-	// create tables if not exist
-	var m *migrate.Migrate
-	var err error
-	m, err = getMigrator()
+// MigrationStatus compares the schema version of a database with the migrations built in
+type MigrationStatus struct {
+	// Version is 0 for a database that was never migrated
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+func Status(path string) (MigrationStatus, error) {
+	status := MigrationStatus{Pending: []uint{}}
+	m, err := getMigrator(path)
 	if err != nil {
-		return err
+		return status, err
 	}
-	uperr := m.Up()
-	if uperr != nil && uperr != migrate.ErrNoChange {
-		m.Close()
-		// attempt recovery force down
-		log.Println("Migration failed, recovering...")
+	defer m.Close()
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
 
-		dB, err := NewDBConnection()
-		if err != nil {
-			log.Println("Migration recovery failed to connect to database")
-			return err
-		}
-		stmt, err := dB.Prepare("SELECT version, dirty FROM schema_migrations LIMIT 1")
-		if err != nil {
-			log.Println("Migration recovery failed to prepare statement to get database migration version")
-			dB.Close()
-			return err
+	src, err := iofs.New(data.GetMigrationFS(), "iofs/migrations")
+	if err != nil {
+		return status, err
+	}
+	defer src.Close()
+	for v, err := src.First(); err == nil; v, err = src.Next(v) {
+		status.Latest = v
+		if v > status.Version {
+			status.Pending = append(status.Pending, v)
 		}
-		version := uint64(0)
-		dirty := false
+	}
+	return status, nil
+}
 
-		row := stmt.QueryRow()
-		err = row.Scan(&version, &dirty)
-		if err != nil {
-			log.Println("Migration recovery failed to fetch db version and dirty state")
-			dB.Close()
-			return err
-		}
-		if !dirty {
-			log.Println("Migration recovery not necessary, schema is not dirty")
-			dB.Close()
-			return uperr
-		}
-		dB.Close()
+// MigrationError is a failed upgrade, Restored tells whether the database is back to how it was before
+type MigrationError struct {
+	Err      error
+	Backup   string
+	Restored bool
+}
 
-		// close db to allow migrate engine to take over
-		m, err = getMigrator()
-		if err != nil {
-			return err
-		}
-		defer m.Close()
-		forceVersion := version - 1
-		log.Println("Migration recovery will force schema back 1 version, to version", forceVersion)
-		err = m.Force(int(forceVersion))
+func (e *MigrationError) Error() string {
+	switch {
+	case e.Backup == "":
+		return "database upgrade failed: " + e.Err.Error()
+	case e.Restored:
+		return "database upgrade failed and the database was restored from " + e.Backup + ": " + e.Err.Error()
+	}
+	return "database upgrade failed and restoring " + e.Backup + " also failed: " + e.Err.Error()
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrDirtySchema is a database left half upgraded, by a crash or a version without automatic restore
+var ErrDirtySchema = errors.New("the database was left half upgraded, restore a backup")
+
+// Migrate applies pending migrations. A database that already has a schema is copied with Backup first
+// and put back from that copy when a migration fails, the backup path is returned either way, empty when none was needed
+func Migrate(path string) (string, error) {
+	status, err := Status(path)
+	if err != nil {
+		return "", err
+	}
+	if status.Dirty {
+		return "", fmt.Errorf("%w, it is at version %d", ErrDirtySchema, status.Version)
+	}
+	if len(status.Pending) == 0 {
+		return "", nil
+	}
+
+	backupPath := ""
+	if status.Version > 0 {
+		backupPath, err = Backup(path, BackupReasonMigrate)
 		if err != nil {
-			log.Println("Migration recovery failed to force version to", forceVersion)
-			return err
+			return "", fmt.Errorf("cannot back up the database before upgrading: %w", err)
 		}
-		log.Println("Migration recovery forced version to", forceVersion)
-		return uperr
 	}
+
+	m, err := getMigrator(path)
+	if err != nil {
+		return backupPath, err
+	}
+	uperr := m.Up()
 	m.Close()
-	return nil
+	if uperr == nil || uperr == migrate.ErrNoChange {
+		return backupPath, nil
+	}
+	migrationErr := &MigrationError{Err: uperr, Backup: backupPath}
+	if backupPath == "" {
+		// a new database has nothing worth keeping, start it over
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(path + suffix)
+		}
+		Create(path)
+		return "", migrationErr
+	}
+	if err := Restore(path, backupPath); err != nil {
+		log.Println("Restoring the database failed", err)
+		return backupPath, migrationErr
+	}
+	migrationErr.Restored = true
+	return backupPath, migrationErr
 }
@@ -0,0 +1,143 @@
+package databaseconn
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Migration 5 rewrites the RFC1123 local times older versions saved into UTC RFC3339
+func TestMigrationRequestHistoryTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := Create(path); err != nil {
+		t.Fatal(err)
+	}
+	m, err := getMigrator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Migrate(4); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := open(path, migrateDSNOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("INSERT INTO song_requests VALUES ('aaaaaaaaaaa', 'Song', 'Band', '')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the zone name was never parsed, the times are read as the local time of the machine
+	local := func(s string) string {
+		tm, err := time.ParseInLocation("Mon, 02 Jan 2006 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm.UTC().Format(time.RFC3339)
+	}
+	tests := []struct {
+		name        string
+		requestedAt string
+		want        string
+	}{
+		{"winter", "Mon, 05 Jan 2026 09:07:03 CET", local("Mon, 05 Jan 2026 09:07:03")},
+		{"summer", "Sat, 18 Jul 2026 23:59:59 CEST", local("Sat, 18 Jul 2026 23:59:59")},
+		{"december", "Thu, 31 Dec 2026 00:00:00 UTC", local("Thu, 31 Dec 2026 00:00:00")},
+		{"unparsable is kept", "yesterday", "yesterday"},
+	}
+	for _, tt := range tests {
+		_, err := db.Exec("INSERT INTO song_request_requesters (video_id, twitch_username, requested_at) VALUES ('aaaaaaaaaaa', ?, ?)", tt.name, tt.requestedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Migrate(5); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			err := db.QueryRow("SELECT requested_at FROM song_request_requesters WHERE twitch_username = ?", tt.name).Scan(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("requested_at = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// a failed upgrade puts back the backup Migrate took, data and version as they were
+func TestMigrateRestoresAfterFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := Create(path); err != nil {
+		t.Fatal(err)
+	}
+	m, err := getMigrator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(7); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	db, err := open(path, migrateDSNOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO song_requests VALUES ('aaaaaaaaaaa', 'Song', 'Band', '')"); err != nil {
+		t.Fatal(err)
+	}
+	// migration 8 creates blocklist and fails on the table already being there
+	if _, err := db.Exec("CREATE TABLE blocklist (value TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO blocklist VALUES ('kept')"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	backupPath, err := Migrate(path)
+	migrationErr := &MigrationError{}
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("Migrate() error = %v, want a MigrationError", err)
+	}
+	if !migrationErr.Restored || backupPath == "" || migrationErr.Backup != backupPath {
+		t.Fatalf("Migrate() = %q, %+v", backupPath, migrationErr)
+	}
+	if _, err := os.Stat(backupPath); err != nil {
+		t.Errorf("the backup is gone: %v", err)
+	}
+
+	status, err := Status(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 7 || status.Dirty {
+		t.Errorf("Status() after restore = %+v, want version 7 and not dirty", status)
+	}
+	db, err = open(path, migrateDSNOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	title := ""
+	if err := db.QueryRow("SELECT song_title FROM song_requests WHERE video_id = 'aaaaaaaaaaa'").Scan(&title); err != nil || title != "Song" {
+		t.Errorf("song after restore = %q, %v", title, err)
+	}
+	value := ""
+	if err := db.QueryRow("SELECT value FROM blocklist").Scan(&value); err != nil || value != "kept" {
+		t.Errorf("blocklist after restore = %q, %v", value, err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM banned_users"); err != nil {
+		t.Errorf("banned_users should still be there: %v", err)
+	}
+}
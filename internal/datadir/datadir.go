@@ -0,0 +1,52 @@
+package datadir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const (
+	appName = "pear-desktop-twitch-song-requests"
+	// EnvVar overrides the default data directory, the -data-dir flag overrides both
+	EnvVar = "PEAR_SR_DATA_DIR"
+)
+
+// Resolve picks the data directory from the flag value, then EnvVar, then the OS default.
+// It does not create anything, see Ensure
+func Resolve(flagValue string) (string, error) {
+	dir := flagValue
+	if dir == "" {
+		dir = os.Getenv(EnvVar)
+	}
+	if dir == "" {
+		return Default()
+	}
+	return filepath.Abs(dir)
+}
+
+// Default is %AppData% on Windows, ~/Library/Application Support on macOS
+// and $XDG_DATA_HOME or ~/.local/share elsewhere
+func Default() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, appName), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("cannot find a data directory, set " + EnvVar + " or -data-dir")
+	}
+	return filepath.Join(home, ".local", "share", appName), nil
+}
+
+func Ensure(dir string) error {
+	return os.MkdirAll(dir, 0o700)
+}
@@ -1,30 +1,45 @@
 package helpers
 
 import (
-	"bufio"
+	"errors"
 	"log"
-	"os"
 
 	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
 )
 
-func PreflightTest() {
+// PreflightTest upgrades the database and logs what happened, the error says what to do next
+func PreflightTest(dbPath string) error {
 	log.Println("Starting preflight tests")
 
-	// Test Connection Postgres Database
 	log.Println("Testing database")
-	// Apply database migrations
-	log.Println("Applying database upgrades")
-	err := databaseconn.Migrate()
+	status, err := databaseconn.Status(dbPath)
+	if err != nil {
+		log.Println("Failed to read the database version")
+		return err
+	}
+	log.Println("Database version", status.Version, "of", status.Latest)
+
+	if len(status.Pending) > 0 && !status.Dirty {
+		log.Println("Applying database upgrades", status.Pending)
+	}
+	backupPath, err := databaseconn.Migrate(dbPath)
+	if backupPath != "" {
+		log.Println("Database backed up to", backupPath)
+	}
 	if err != nil {
-		log.Println("Failed to apply database upgrades")
 		log.Println(err)
-		log.Println("Press Enter to exit...")
-		bufio.NewReader(os.Stdin).ReadBytes('\n')
-		os.Exit(1)
+		migrationErr := &databaseconn.MigrationError{}
+		switch {
+		case errors.As(err, &migrationErr) && migrationErr.Restored:
+			log.Println("Nothing was changed, the previous version of the app still works with this database")
+		case errors.As(err, &migrationErr) && migrationErr.Backup != "":
+			log.Println("Run this app with `migrate restore " + migrationErr.Backup + "` to put the database back")
+		case errors.Is(err, databaseconn.ErrDirtySchema):
+			log.Println("Run this app with `migrate status` to see the backups and `migrate restore` to restore the newest one")
+		}
+		return err
 	}
 
-	// None for now
-	log.Println("Database upgrades successfully applied")
 	log.Println("Preflight Test Completed")
+	return nil
 }
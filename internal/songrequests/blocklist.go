@@ -0,0 +1,115 @@
+package songrequests
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type BlocklistKind = string
+
+const (
+	// Value is the Twitch user ID, label the login at the time of the ban
+	BlocklistKindUser BlocklistKind = "user"
+	// Value is the videoId, label the title and artist
+	BlocklistKindVideo BlocklistKind = "video"
+	// Value is the artist name, compared case insensitive with the whole artist
+	// and with each artist credited in it, like "A" and "B" in "A & B" or "A feat. B"
+	BlocklistKindArtist BlocklistKind = "artist"
+	// Value is a word or phrase found anywhere in the title, case insensitive
+	BlocklistKindKeyword BlocklistKind = "keyword"
+	// Value is a regular expression matched against the title, case insensitive
+	BlocklistKindRegex BlocklistKind = "regex"
+)
+
+var BlocklistKinds = []BlocklistKind{BlocklistKindUser, BlocklistKindVideo, BlocklistKindArtist, BlocklistKindKeyword, BlocklistKindRegex}
+
+// SongBlocklistKinds are the kinds checked against the requested song
+var SongBlocklistKinds = []BlocklistKind{BlocklistKindVideo, BlocklistKindArtist, BlocklistKindKeyword, BlocklistKindRegex}
+
+// NormalizeBlocklistValue checks a value before it is saved, links become videoIds
+func NormalizeBlocklistValue(kind BlocklistKind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("value is required")
+	}
+	switch kind {
+	case BlocklistKindUser:
+		for _, r := range value {
+			if r < '0' || r > '9' {
+				return "", errors.New("user must be a Twitch user ID")
+			}
+		}
+	case BlocklistKindVideo:
+		value = ParseSearchQuery(value)
+		if !IsVideoID(value) {
+			return "", errors.New("song must be a videoId or a YouTube link")
+		}
+	case BlocklistKindArtist, BlocklistKindKeyword:
+		// matching ignores case, so the saved value does too and one entry covers every spelling
+		value = strings.ToLower(value)
+	case BlocklistKindRegex:
+		if _, err := regexp.Compile("(?i)" + value); err != nil {
+			return "", errors.New("invalid regex: " + err.Error())
+		}
+	default:
+		return "", errors.New("kind must be one of " + strings.Join(BlocklistKinds, ", "))
+	}
+	return value, nil
+}
+
+func IsBlocklistKind(s string) bool {
+	return slices.Contains(BlocklistKinds, s)
+}
+
+// SongBlocker is a song blocklist entry ready to match, a regex is compiled once when it is made
+type SongBlocker struct {
+	Kind  BlocklistKind
+	Value string
+	re    *regexp.Regexp
+}
+
+// NewSongBlocker fails for a regex that does not compile
+func NewSongBlocker(kind BlocklistKind, value string) (SongBlocker, error) {
+	b := SongBlocker{Kind: kind, Value: value}
+	if kind == BlocklistKindRegex {
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return b, err
+		}
+		b.re = re
+	}
+	return b, nil
+}
+
+// Blocks is true when the entry rules out the song, user entries never do
+func (b SongBlocker) Blocks(song *SongResult) bool {
+	switch b.Kind {
+	case BlocklistKindVideo:
+		return song.VideoID == b.Value
+	case BlocklistKindArtist:
+		return slices.ContainsFunc(creditedArtists(song.Artist), func(artist string) bool {
+			return strings.EqualFold(artist, b.Value)
+		})
+	case BlocklistKindKeyword:
+		return strings.Contains(strings.ToLower(song.Title), strings.ToLower(b.Value))
+	case BlocklistKindRegex:
+		return b.re != nil && b.re.MatchString(song.Title)
+	}
+	return false
+}
+
+var artistCreditSeparators = strings.NewReplacer(",", "\x00", "&", "\x00", " feat. ", "\x00", " ft. ", "\x00", " featuring ", "\x00")
+
+// creditedArtists is the whole artist followed by each artist credited in it
+func creditedArtists(artist string) []string {
+	artist = strings.TrimSpace(artist)
+	artists := []string{artist}
+	for _, v := range strings.Split(artistCreditSeparators.Replace(strings.ToLower(artist)), "\x00") {
+		if v = strings.TrimSpace(v); v != "" && v != strings.ToLower(artist) {
+			artists = append(artists, v)
+		}
+	}
+	return artists
+}
@@ -0,0 +1,78 @@
+package songrequests
+
+import "testing"
+
+func TestNormalizeBlocklistValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    BlocklistKind
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"user id", BlocklistKindUser, " 12345 ", "12345", false},
+		{"user login is not an id", BlocklistKindUser, "troll", "", true},
+		{"video link", BlocklistKindVideo, "https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"video id", BlocklistKindVideo, "dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"not a video", BlocklistKindVideo, "never gonna", "", true},
+		{"artist is lowercased", BlocklistKindArtist, "Nickelback", "nickelback", false},
+		{"keyword is lowercased", BlocklistKindKeyword, " Nightcore ", "nightcore", false},
+		{"regex keeps its case", BlocklistKindRegex, `\bREMIX\b`, `\bREMIX\b`, false},
+		{"broken regex", BlocklistKindRegex, "(", "", true},
+		{"empty", BlocklistKindArtist, "  ", "", true},
+		{"unknown kind", "genre", "metal", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeBlocklistValue(tt.kind, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeBlocklistValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeBlocklistValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSongBlocker(t *testing.T) {
+	song := &SongResult{VideoID: "dQw4w9WgXcQ", Title: "Photograph (Nightcore Remix)", Artist: "Nickelback"}
+	featuring := &SongResult{VideoID: "aaaaaaaaaaa", Title: "Song", Artist: "Some Band, Nickelback & Other Band feat. Guest"}
+	tests := []struct {
+		name  string
+		kind  BlocklistKind
+		value string
+		song  *SongResult
+		want  bool
+	}{
+		{"video", BlocklistKindVideo, "dQw4w9WgXcQ", song, true},
+		{"other video", BlocklistKindVideo, "aaaaaaaaaaa", song, false},
+		{"artist", BlocklistKindArtist, "nickelback", song, true},
+		{"artist must match whole", BlocklistKindArtist, "nickel", song, false},
+		{"credited artist", BlocklistKindArtist, "nickelback", featuring, true},
+		{"last credited artist", BlocklistKindArtist, "other band", featuring, true},
+		{"featured artist", BlocklistKindArtist, "guest", featuring, true},
+		{"every credited artist", BlocklistKindArtist, "some band, nickelback & other band feat. guest", featuring, true},
+		{"credited artist must match whole", BlocklistKindArtist, "band", featuring, false},
+		{"keyword", BlocklistKindKeyword, "nightcore", song, true},
+		{"regex", BlocklistKindRegex, `\bremix\b`, song, true},
+		{"regex ignores case", BlocklistKindRegex, `^PHOTOGRAPH`, song, true},
+		{"regex miss", BlocklistKindRegex, `^remix`, song, false},
+		{"user entries never block songs", BlocklistKindUser, "12345", song, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewSongBlocker(tt.kind, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Blocks(tt.song); got != tt.want {
+				t.Errorf("Blocks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSongBlocker(BlocklistKindRegex, "("); err == nil {
+		t.Error("NewSongBlocker() with a broken regex should fail")
+	}
+}
@@ -2,9 +2,22 @@ package songrequests
 
 import (
 	"net/url"
+	"regexp"
 	"strings"
 )
 
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// IsVideoID is true for strings shaped like a YouTube videoId
+func IsVideoID(s string) bool {
+	return videoIDPattern.MatchString(s)
+}
+
+// NormalizeSearchQuery makes repeat requests typed slightly differently share one search cache entry
+func NormalizeSearchQuery(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
 func ParseSearchQuery(s string) string {
 	s = strings.TrimPrefix(s, "!sr ")
 	url, err := url.Parse(s)
@@ -0,0 +1,140 @@
+package songrequests
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+type ChatterRole = string
+
+const (
+	ChatterRoleEveryone    ChatterRole = "everyone"
+	ChatterRoleFollower    ChatterRole = "follower"
+	ChatterRoleSubscriber  ChatterRole = "subscriber"
+	ChatterRoleFounder     ChatterRole = "founder"
+	ChatterRoleVIP         ChatterRole = "vip"
+	ChatterRoleModerator   ChatterRole = "moderator"
+	ChatterRoleBroadcaster ChatterRole = "broadcaster"
+)
+
+var validChatterRoles = []ChatterRole{
+	ChatterRoleEveryone,
+	ChatterRoleFollower,
+	ChatterRoleSubscriber,
+	ChatterRoleFounder,
+	ChatterRoleVIP,
+	ChatterRoleModerator,
+	ChatterRoleBroadcaster,
+}
+
+type ChatterRoles struct {
+	IsBroadcaster bool
+	IsModerator   bool
+	IsVIP         bool
+	IsSubscriber  bool
+	IsFounder     bool
+	IsFollower    bool
+	// FollowerChecked is false until the follow status was looked up, badges never carry it
+	FollowerChecked bool
+}
+
+func (r ChatterRoles) Has(role ChatterRole) bool {
+	switch role {
+	case ChatterRoleEveryone:
+		return true
+	case ChatterRoleFollower:
+		return r.IsFollower
+	case ChatterRoleSubscriber:
+		return r.IsSubscriber
+	case ChatterRoleFounder:
+		return r.IsFounder
+	case ChatterRoleVIP:
+		return r.IsVIP
+	case ChatterRoleModerator:
+		return r.IsModerator
+	case ChatterRoleBroadcaster:
+		return r.IsBroadcaster
+	}
+	return false
+}
+
+// Only valid for chat messages sent in the channel the badges belong to
+func ChatterRolesFromBadges(badges []twitch.ChatMessageUserBadge) ChatterRoles {
+	roles := ChatterRoles{}
+	for _, v := range badges {
+		switch v.SetId {
+		case "broadcaster":
+			roles.IsBroadcaster = true
+		case "moderator":
+			roles.IsModerator = true
+		case "vip":
+			roles.IsVIP = true
+		case "subscriber":
+			roles.IsSubscriber = true
+		case "founder":
+			// founders show this badge instead of the subscriber one
+			roles.IsFounder = true
+			roles.IsSubscriber = true
+		}
+	}
+	return roles
+}
+
+// PermissionRule is the list of roles allowed to use a command, the broadcaster is always allowed
+type PermissionRule []ChatterRole
+
+func ParsePermissionRule(s string) (PermissionRule, error) {
+	rule := PermissionRule{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		valid := false
+		for _, role := range validChatterRoles {
+			if v == role {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.New("permission rule: unknown role " + v)
+		}
+		rule = append(rule, v)
+	}
+	if len(rule) == 0 {
+		return nil, errors.New("permission rule: no roles")
+	}
+	return rule, nil
+}
+
+func (r PermissionRule) String() string {
+	return strings.Join(r, ",")
+}
+
+func (r PermissionRule) Allows(roles ChatterRoles) bool {
+	if roles.IsBroadcaster {
+		return true
+	}
+	for _, role := range r {
+		if roles.Has(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsFollowerCheck reports whether only a follow lookup can still grant access
+func (r PermissionRule) NeedsFollowerCheck(roles ChatterRoles) bool {
+	if roles.FollowerChecked || r.Allows(roles) {
+		return false
+	}
+	for _, role := range r {
+		if role == ChatterRoleFollower {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package songrequests
+
+import "testing"
+
+func TestParsePermissionRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"single role", "moderator", "moderator", false},
+		{"several roles", "subscriber,vip,moderator", "subscriber,vip,moderator", false},
+		{"spaces and case", " Subscriber , VIP ", "subscriber,vip", false},
+		{"empty entries are skipped", "follower,,founder,", "follower,founder", false},
+		{"unknown role", "subscriber,admin", "", true},
+		{"empty", "", "", true},
+		{"only commas", " , ", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePermissionRule(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePermissionRule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParsePermissionRule(%q) = %q, want %q", tt.in, got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionRuleAllows(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      PermissionRule
+		roles     ChatterRoles
+		want      bool
+		wantCheck bool
+	}{
+		{"everyone", PermissionRule{ChatterRoleEveryone}, ChatterRoles{}, true, false},
+		{"broadcaster is always allowed", PermissionRule{ChatterRoleModerator}, ChatterRoles{IsBroadcaster: true}, true, false},
+		{"moderator", PermissionRule{ChatterRoleModerator}, ChatterRoles{IsModerator: true}, true, false},
+		{"vip is not a moderator", PermissionRule{ChatterRoleModerator}, ChatterRoles{IsVIP: true}, false, false},
+		{"any listed role", PermissionRule{ChatterRoleSubscriber, ChatterRoleVIP}, ChatterRoles{IsVIP: true}, true, false},
+		{"founder", PermissionRule{ChatterRoleFounder}, ChatterRoles{IsFounder: true, IsSubscriber: true}, true, false},
+		{"subscriber is not a founder", PermissionRule{ChatterRoleFounder}, ChatterRoles{IsSubscriber: true}, false, false},
+		{"follower not looked up yet", PermissionRule{ChatterRoleFollower}, ChatterRoles{}, false, true},
+		{"follower looked up", PermissionRule{ChatterRoleFollower}, ChatterRoles{IsFollower: true, FollowerChecked: true}, true, false},
+		{"not a follower", PermissionRule{ChatterRoleFollower}, ChatterRoles{FollowerChecked: true}, false, false},
+		{"other role skips the follow lookup", PermissionRule{ChatterRoleFollower, ChatterRoleVIP}, ChatterRoles{IsVIP: true}, true, false},
+		{"nobody", PermissionRule{ChatterRoleBroadcaster}, ChatterRoles{IsModerator: true, IsVIP: true}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Allows(tt.roles); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+			if got := tt.rule.NeedsFollowerCheck(tt.roles); got != tt.wantCheck {
+				t.Errorf("NeedsFollowerCheck() = %v, want %v", got, tt.wantCheck)
+			}
+		})
+	}
+}
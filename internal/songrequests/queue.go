@@ -0,0 +1,183 @@
+package songrequests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RequestSource = string
+
+const (
+	RequestSourceChat   RequestSource = "chat"
+	RequestSourceReward RequestSource = "reward"
+	RequestSourceAPI    RequestSource = "api"
+)
+
+// RequestOutcome is kept up to date in the request history as the request moves on,
+// rejected requests also keep the rejection reason
+type RequestOutcome = string
+
+const (
+	RequestOutcomeQueued   RequestOutcome = "queued"
+	RequestOutcomePlayed   RequestOutcome = "played"
+	RequestOutcomeSkipped  RequestOutcome = "skipped"
+	RequestOutcomeRemoved  RequestOutcome = "removed"
+	RequestOutcomeRejected RequestOutcome = "rejected"
+)
+
+type PearQueueItem struct {
+	PlaylistPanelVideoRenderer struct {
+		VideoId         string `json:"videoId"`
+		Selected        bool   `json:"selected"`
+		ShortByLineText struct {
+			Runs []struct {
+				Text string `json:"text"`
+			} `json:"runs"`
+		} `json:"shortByLineText"`
+		Title struct {
+			Runs []struct {
+				Text string `json:"text"`
+			} `json:"runs"`
+		} `json:"title"`
+		LengthText struct {
+			Runs []struct {
+				Text string `json:"text"`
+			} `json:"runs"`
+		} `json:"lengthText"`
+		Thumbnail struct {
+			Thumbnails []struct {
+				Url string `json:"url"`
+			} `json:"thumbnails"`
+		} `json:"thumbnail"`
+	} `json:"playlistPanelVideoRenderer"`
+}
+
+func (i PearQueueItem) VideoID() string {
+	return i.PlaylistPanelVideoRenderer.VideoId
+}
+
+func (i PearQueueItem) Title() string {
+	if len(i.PlaylistPanelVideoRenderer.Title.Runs) == 0 {
+		return ""
+	}
+	return i.PlaylistPanelVideoRenderer.Title.Runs[0].Text
+}
+
+func (i PearQueueItem) Artist() string {
+	if len(i.PlaylistPanelVideoRenderer.ShortByLineText.Runs) == 0 {
+		return ""
+	}
+	return i.PlaylistPanelVideoRenderer.ShortByLineText.Runs[0].Text
+}
+
+func (i PearQueueItem) Length() string {
+	if len(i.PlaylistPanelVideoRenderer.LengthText.Runs) == 0 {
+		return ""
+	}
+	return i.PlaylistPanelVideoRenderer.LengthText.Runs[0].Text
+}
+
+func (i PearQueueItem) ImageUrl() string {
+	thumbnails := i.PlaylistPanelVideoRenderer.Thumbnail.Thumbnails
+	if len(thumbnails) == 0 {
+		return ""
+	}
+	return thumbnails[len(thumbnails)-1].Url
+}
+
+type PearQueue struct {
+	Items []PearQueueItem `json:"items"`
+}
+
+// SelectedIndex is the index of the song playing now, -1 when nothing is selected
+func (q PearQueue) SelectedIndex() int {
+	for i, v := range q.Items {
+		if v.PlaylistPanelVideoRenderer.Selected {
+			return i
+		}
+	}
+	return -1
+}
+
+// IndexAfterSelected finds a song that plays after the current one, -1 when it is not queued
+func (q PearQueue) IndexAfterSelected(videoID string) int {
+	nowIndex := q.SelectedIndex()
+	if nowIndex == -1 {
+		return -1
+	}
+	for i := nowIndex + 1; i < len(q.Items); i++ {
+		if q.Items[i].VideoID() == videoID {
+			return i
+		}
+	}
+	return -1
+}
+
+func GetPearQueue() (*PearQueue, error) {
+	resp, err := http.Get("http://" + pearDesktopHost + "/api/v1/queue")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("get queue: unexpected status " + resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	queue := PearQueue{}
+	err = json.Unmarshal(b, &queue)
+	if err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+func RemoveFromPearQueue(index int) error {
+	req, _ := http.NewRequest(http.MethodDelete, "http://"+pearDesktopHost+"/api/v1/queue/"+strconv.Itoa(index), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.New("remove from queue: unexpected status " + resp.Status)
+	}
+	return nil
+}
+
+func MoveInPearQueue(index int, toIndex int) error {
+	b, _ := json.Marshal(echo.Map{
+		"toIndex": toIndex,
+	})
+	req, _ := http.NewRequest(http.MethodPatch, "http://"+pearDesktopHost+"/api/v1/queue/"+strconv.Itoa(index), bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.New("move in queue: unexpected status " + resp.Status)
+	}
+	return nil
+}
+
+func SkipPearSong() error {
+	resp, err := http.Post("http://"+pearDesktopHost+"/api/v1/next", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return errors.New("skip song: unexpected status " + resp.Status)
+	}
+	return nil
+}
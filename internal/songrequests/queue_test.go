@@ -0,0 +1,49 @@
+package songrequests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSkipPearSong(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"skipped", http.StatusNoContent, false},
+		{"pear desktop error", http.StatusInternalServerError, true},
+		{"not authorized", http.StatusUnauthorized, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/api/v1/next" {
+					t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			old := pearDesktopHost
+			pearDesktopHost = strings.TrimPrefix(srv.URL, "http://")
+			defer func() { pearDesktopHost = old }()
+
+			if err := SkipPearSong(); (err != nil) != tt.wantErr {
+				t.Errorf("SkipPearSong() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// pear desktop not running
+	srv := httptest.NewServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(srv.URL, "http://")
+	srv.Close()
+	old := pearDesktopHost
+	pearDesktopHost = host
+	defer func() { pearDesktopHost = old }()
+	if err := SkipPearSong(); err == nil {
+		t.Error("SkipPearSong() without Pear Desktop should fail")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,8 +26,16 @@ type SongResult struct {
 	RawTimeData  string `json:"-"`
 	ImageUrl     string `json:"imageUrl"`
 	SearchOrigin string `json:"-"`
+	// DurationSeconds is 0 when the search did not show a length
+	DurationSeconds int    `json:"-"`
+	VideoType       string `json:"-"`
 }
 
+var (
+	ErrNoResults          = errors.New("search songs: no results")
+	ErrDurationNotAllowed = errors.New("search songs: song duration exceeds max allowed")
+)
+
 type apiSearchSongResult struct {
 	Contents struct {
 		TabbedSearchResultsRenderer struct {
@@ -150,6 +159,18 @@ const (
 
 // make sure to sanitize url for music.youtube.com / youtu.be / youtube.com/watch?v=
 func SearchSong(query string, minLength int, maxLength int) (*SongResult, error) {
+	song, err := FindSong(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckDuration(song, minLength, maxLength); err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// FindSong asks Pear Desktop's search for the query and picks a result without checking its length
+func FindSong(query string) (*SongResult, error) {
 	inBody := echo.Map{
 		"query": strings.TrimSpace(query),
 	}
@@ -339,6 +360,7 @@ func SearchSong(query string, minLength int, maxLength int) (*SongResult, error)
 						RawTimeData:  timeData,
 						ImageUrl:     imageUrl,
 						SearchOrigin: "MusicShelfRenderer",
+						VideoType:    mediaType,
 					})
 				}
 			}
@@ -359,16 +381,38 @@ func SearchSong(query string, minLength int, maxLength int) (*SongResult, error)
 	}
 
 	if selectedSong != nil {
-		// "1:00:04" or "10:00" or "1:00" or err
-		// err usually means its safe
-		isValid := validateTime(selectedSong.RawTimeData, minLength, maxLength)
-		if !isValid {
-			return nil, errors.New("search songs: song duration exceeds max allowed")
-		}
+		selectedSong.DurationSeconds = parseDuration(selectedSong.RawTimeData)
 		return selectedSong, nil
 	}
 
-	return nil, errors.New("search songs: no results")
+	return nil, ErrNoResults
+}
+
+// CheckDuration applies the !sr length limits, in seconds
+func CheckDuration(song *SongResult, minLength int, maxLength int) error {
+	// "1:00:04" or "10:00" or "1:00" or err
+	// err usually means its safe
+	if !validateTime(song.RawTimeData, minLength, maxLength) {
+		return ErrDurationNotAllowed
+	}
+	return nil
+}
+
+// parseDuration reads "1:00:04", "10:00" or "1:00", anything else is 0
+func parseDuration(s string) int {
+	seconds := 0
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0
+	}
+	for _, v := range parts {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
 }
 
 func validateTime(s string, min, max int) bool {
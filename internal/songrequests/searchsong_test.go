@@ -0,0 +1,63 @@
+package songrequests
+
+import "testing"
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"1:00", 60},
+		{"10:00", 600},
+		{"3:07", 187},
+		{"1:00:04", 3604},
+		{"0:00", 0},
+		{"", 0},
+		{"42", 0},
+		{"1:2:3:4", 0},
+		{"1:-5", 0},
+		{"LIVE", 0},
+		{"1:xx", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parseDuration(tt.in); got != tt.want {
+				t.Errorf("parseDuration(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Never Gonna Give You Up", "never gonna give you up"},
+		{"  never   gonna\tgive you up ", "never gonna give you up"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSearchQuery(tt.in); got != tt.want {
+			t.Errorf("NormalizeSearchQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsVideoID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"dQw4w9WgXcQ", true},
+		{"a_b-c_d-e_f", true},
+		{"dQw4w9WgXc", false},
+		{"dQw4w9WgXcQQ", false},
+		{"never gonna", false},
+	}
+	for _, tt := range tests {
+		if got := IsVideoID(tt.in); got != tt.want {
+			t.Errorf("IsVideoID(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package songrequests
+
+import (
+	"github.com/joeyak/go-twitch-eventsub/v3"
+)
+
+type SharedChatRequestMode = string
+
+const (
+	// Partner channel chatters request like our own chatters, with their roles in our channel
+	SharedChatRequestModeAllow SharedChatRequestMode = "allow"
+	// Partner channel chatters cannot request
+	SharedChatRequestModeDeny SharedChatRequestMode = "deny"
+	// Partner channel chatters also need one of the shared chat permission roles in our channel
+	SharedChatRequestModeRestrict SharedChatRequestMode = "restrict"
+)
+
+// IsSharedChatPartnerMessage reports whether the message was sent in another channel of a Shared Chat session.
+// Source fields are empty outside of Shared Chat and point to our own channel for our own chatters.
+// MessageId is the id of the copy in our channel, so replies with it and our BroadcasterUserId land in our chat
+// and Twitch shares them back to the partner channels, SourceMessageId is only valid in the partner channel.
+func IsSharedChatPartnerMessage(event twitch.EventChannelChatMessage) bool {
+	return event.SourceBroadcasterUserId != "" && event.SourceBroadcasterUserId != event.BroadcasterUserId
+}
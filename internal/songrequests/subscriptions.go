@@ -10,6 +10,18 @@ func GetSubscriptions() []twitch.EventSubscription {
 		twitch.SubStreamOffline,
 		twitch.SubChannelChatMessage,
 		twitch.SubChannelChannelPointsCustomRewardRedemptionAdd, // claim reward points
+		// role changes, keep the bot mode role cache fresh
+		twitch.SubChannelModeratorAdd,
+		twitch.SubChannelModeratorRemove,
+		twitch.SubChannelSubscribe,
+		twitch.SubChannelSubscriptionEnd,
+		twitch.SubChannelVIPAdd,
+		twitch.SubChannelVIPRemove,
+		// request credits
+		twitch.SubChannelCheer,
+		twitch.SubChannelSubscriptionGift,
+		twitch.SubChannelSubscriptionMessage,
+		twitch.SubChannelRaid,
 	}
 
 	return events
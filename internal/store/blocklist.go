@@ -0,0 +1,67 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+// BlocklistRepo holds every kind of blocklist entry, values are expected to be normalized already
+type BlocklistRepo interface {
+	// Add returns false when the entry was already listed, it is left as it was
+	Add(ctx context.Context, kind songrequests.BlocklistKind, value string, label string) (bool, error)
+	// Remove returns false when the entry was not listed
+	Remove(ctx context.Context, kind songrequests.BlocklistKind, value string) (bool, error)
+	// List is newest first, every kind when none are given
+	List(ctx context.Context, kinds ...songrequests.BlocklistKind) ([]model.Blocklist, error)
+}
+
+type sqliteBlocklist struct {
+	db *sql.DB
+}
+
+func (r *sqliteBlocklist) Add(ctx context.Context, kind songrequests.BlocklistKind, value string, label string) (bool, error) {
+	stmt := Blocklist.INSERT(Blocklist.AllColumns).MODEL(model.Blocklist{
+		Kind:      kind,
+		Value:     value,
+		Label:     label,
+		CreatedAt: FormatTime(time.Now()),
+	}).ON_CONFLICT(Blocklist.Kind, Blocklist.Value).DO_NOTHING()
+	res, err := stmt.ExecContext(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *sqliteBlocklist) Remove(ctx context.Context, kind songrequests.BlocklistKind, value string) (bool, error) {
+	stmt := Blocklist.DELETE().WHERE(Blocklist.Kind.EQ(String(kind)).AND(Blocklist.Value.EQ(String(value))))
+	res, err := stmt.ExecContext(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *sqliteBlocklist) List(ctx context.Context, kinds ...songrequests.BlocklistKind) ([]model.Blocklist, error) {
+	condition := Bool(true)
+	if len(kinds) > 0 {
+		kindExpressions := []Expression{}
+		for _, v := range kinds {
+			kindExpressions = append(kindExpressions, String(v))
+		}
+		condition = Blocklist.Kind.IN(kindExpressions...)
+	}
+	results := []model.Blocklist{}
+	stmt := SELECT(Blocklist.AllColumns).FROM(Blocklist).WHERE(condition).ORDER_BY(Blocklist.CreatedAt.DESC(), Blocklist.Kind, Blocklist.Value)
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
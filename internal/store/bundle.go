@@ -0,0 +1,455 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/go-jet/jet/v2/qrm"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+// BundleVersion is bumped whenever the bundle layout changes in a way older imports can't read
+const BundleVersion = 1
+
+// Bundle is the whole database as portable json, history rows keep their ids only so plays can point at requests
+type Bundle struct {
+	Version       int                   `json:"version"`
+	ExportedAt    string                `json:"exported_at"`
+	IncludeTokens bool                  `json:"include_tokens"`
+	Settings      []BundleSetting       `json:"settings"`
+	Songs         []BundleSong          `json:"songs"`
+	Requests      []BundleRequest       `json:"requests"`
+	Plays         []BundlePlay          `json:"plays"`
+	Credits       []BundleCredit        `json:"credits"`
+	Blocklist     []BundleBlocklistItem `json:"blocklist"`
+}
+
+type BundleSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type BundleSong struct {
+	VideoID    string `json:"video_id"`
+	SongTitle  string `json:"song_title"`
+	ArtistName string `json:"artist_name"`
+	ImageURL   string `json:"image_url"`
+}
+
+type BundleRequest struct {
+	ID             int32  `json:"id"`
+	VideoID        string `json:"video_id"`
+	TwitchUserID   string `json:"twitch_user_id"`
+	TwitchUsername string `json:"twitch_username"`
+	RequestedAt    string `json:"requested_at"`
+	Source         string `json:"source"`
+	Outcome        string `json:"outcome"`
+	OutcomeReason  string `json:"outcome_reason"`
+	OutcomeAt      string `json:"outcome_at"`
+}
+
+type BundlePlay struct {
+	VideoID         string `json:"video_id"`
+	SongTitle       string `json:"song_title"`
+	ArtistName      string `json:"artist_name"`
+	ImageURL        string `json:"image_url"`
+	DurationSeconds int32  `json:"duration_seconds"`
+	StartedAt       string `json:"started_at"`
+	EndedAt         string `json:"ended_at"`
+	PlayedSeconds   int32  `json:"played_seconds"`
+	Skipped         bool   `json:"skipped"`
+	SkippedBy       string `json:"skipped_by"`
+	RequestID       int32  `json:"request_id,omitempty"`
+}
+
+type BundleCredit struct {
+	TwitchUserID   string `json:"twitch_user_id"`
+	TwitchUsername string `json:"twitch_username"`
+	Credits        int32  `json:"credits"`
+}
+
+type BundleBlocklistItem struct {
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+	Label     string `json:"label"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ExportOptions struct {
+	// IncludeTokens also exports the twitch tokens and the session secret
+	IncludeTokens bool
+}
+
+type ImportMode string
+
+const (
+	// ImportSkip keeps what is already in the database when a setting, song, credit or blocklist entry exists
+	ImportSkip ImportMode = "skip"
+	// ImportOverwrite replaces it with the bundle's value
+	ImportOverwrite ImportMode = "overwrite"
+)
+
+func ParseImportMode(s string) (ImportMode, error) {
+	switch ImportMode(s) {
+	case "", ImportSkip:
+		return ImportSkip, nil
+	case ImportOverwrite:
+		return ImportOverwrite, nil
+	}
+	return "", fmt.Errorf("unknown import mode %q, expected %s or %s", s, ImportSkip, ImportOverwrite)
+}
+
+type ImportCounts struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+type ImportResult struct {
+	Settings  ImportCounts `json:"settings"`
+	Songs     ImportCounts `json:"songs"`
+	Requests  ImportCounts `json:"requests"`
+	Plays     ImportCounts `json:"plays"`
+	Credits   ImportCounts `json:"credits"`
+	Blocklist ImportCounts `json:"blocklist"`
+	// ChangedSettings are the keys that were written, the app has to reapply them
+	ChangedSettings []string `json:"changed_settings"`
+}
+
+func (s *Store) Export(ctx context.Context, opts ExportOptions) (Bundle, error) {
+	b := Bundle{
+		Version:       BundleVersion,
+		ExportedAt:    FormatTime(time.Now()),
+		IncludeTokens: opts.IncludeTokens,
+		Settings:      []BundleSetting{},
+		Songs:         []BundleSong{},
+		Requests:      []BundleRequest{},
+		Plays:         []BundlePlay{},
+		Credits:       []BundleCredit{},
+		Blocklist:     []BundleBlocklistItem{},
+	}
+
+	// through the repo so tokens come out decrypted, the key stays with this database
+	settings, err := s.Settings.All(ctx)
+	if err != nil {
+		return b, err
+	}
+	slices.SortFunc(settings, func(a, b model.Settings) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	for _, v := range settings {
+		if data.IsTokenKeySetting(v.Key) || (!opts.IncludeTokens && data.IsSecretSetting(v.Key)) {
+			continue
+		}
+		b.Settings = append(b.Settings, BundleSetting{Key: v.Key, Value: v.Value})
+	}
+
+	songs := []model.SongRequests{}
+	err = SELECT(SongRequests.AllColumns).FROM(SongRequests).ORDER_BY(SongRequests.VideoID).QueryContext(ctx, s.db, &songs)
+	if err != nil {
+		return b, err
+	}
+	for _, v := range songs {
+		b.Songs = append(b.Songs, BundleSong(v))
+	}
+
+	requests := []model.SongRequestRequesters{}
+	err = SELECT(SongRequestRequesters.AllColumns).FROM(SongRequestRequesters).ORDER_BY(SongRequestRequesters.ID).QueryContext(ctx, s.db, &requests)
+	if err != nil {
+		return b, err
+	}
+	for _, v := range requests {
+		b.Requests = append(b.Requests, BundleRequest{
+			ID:             derefInt32(v.ID),
+			VideoID:        v.VideoID,
+			TwitchUserID:   v.TwitchUserID,
+			TwitchUsername: v.TwitchUsername,
+			RequestedAt:    v.RequestedAt,
+			Source:         v.Source,
+			Outcome:        v.Outcome,
+			OutcomeReason:  v.OutcomeReason,
+			OutcomeAt:      v.OutcomeAt,
+		})
+	}
+
+	plays := []model.Plays{}
+	err = SELECT(Plays.AllColumns).FROM(Plays).ORDER_BY(Plays.ID).QueryContext(ctx, s.db, &plays)
+	if err != nil {
+		return b, err
+	}
+	for _, v := range plays {
+		b.Plays = append(b.Plays, BundlePlay{
+			VideoID:         v.VideoID,
+			SongTitle:       v.SongTitle,
+			ArtistName:      v.ArtistName,
+			ImageURL:        v.ImageURL,
+			DurationSeconds: v.DurationSeconds,
+			StartedAt:       v.StartedAt,
+			EndedAt:         v.EndedAt,
+			PlayedSeconds:   v.PlayedSeconds,
+			Skipped:         v.Skipped,
+			SkippedBy:       v.SkippedBy,
+			RequestID:       derefInt32(v.RequestID),
+		})
+	}
+
+	credits := []model.RequestCredits{}
+	err = SELECT(RequestCredits.AllColumns).FROM(RequestCredits).ORDER_BY(RequestCredits.TwitchUserID).QueryContext(ctx, s.db, &credits)
+	if err != nil {
+		return b, err
+	}
+	for _, v := range credits {
+		b.Credits = append(b.Credits, BundleCredit(v))
+	}
+
+	blocklist := []model.Blocklist{}
+	err = SELECT(Blocklist.AllColumns).FROM(Blocklist).ORDER_BY(Blocklist.Kind, Blocklist.Value).QueryContext(ctx, s.db, &blocklist)
+	if err != nil {
+		return b, err
+	}
+	for _, v := range blocklist {
+		b.Blocklist = append(b.Blocklist, BundleBlocklistItem(v))
+	}
+	return b, nil
+}
+
+// Import merges a bundle into the database in one transaction.
+// Requests and plays are history and are never overwritten, a row that is already there
+// (same video, requester and time, or same video and start time) is skipped so importing twice is harmless.
+func (s *Store) Import(ctx context.Context, b Bundle, mode ImportMode) (ImportResult, error) {
+	result := ImportResult{ChangedSettings: []string{}}
+	if b.Version < 1 || b.Version > BundleVersion {
+		return result, fmt.Errorf("unsupported bundle version %d, this build reads up to %d", b.Version, BundleVersion)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	for _, v := range b.Settings {
+		if v.Key == "" || data.IsTokenKeySetting(v.Key) {
+			continue
+		}
+		exists, err := rowExists(ctx, tx, Settings, Settings.Key.EQ(String(v.Key)))
+		if err != nil {
+			return result, err
+		}
+		stmt := Settings.INSERT(Settings.AllColumns).MODEL(model.Settings{Key: v.Key, Value: s.sealSetting(v.Key, v.Value)})
+		if exists {
+			if mode != ImportOverwrite {
+				result.Settings.Skipped++
+				continue
+			}
+			stmt = stmt.ON_CONFLICT(Settings.Key).DO_UPDATE(SET(Settings.Value.SET(Settings.EXCLUDED.Value)))
+		}
+		if _, err := stmt.ExecContext(ctx, tx); err != nil {
+			return result, fmt.Errorf("setting %s: %w", v.Key, err)
+		}
+		countImported(&result.Settings, exists)
+		result.ChangedSettings = append(result.ChangedSettings, v.Key)
+	}
+
+	for _, v := range b.Songs {
+		exists, err := rowExists(ctx, tx, SongRequests, SongRequests.VideoID.EQ(String(v.VideoID)))
+		if err != nil {
+			return result, err
+		}
+		stmt := SongRequests.INSERT(SongRequests.AllColumns).MODEL(model.SongRequests(v))
+		if exists {
+			if mode != ImportOverwrite {
+				result.Songs.Skipped++
+				continue
+			}
+			stmt = stmt.ON_CONFLICT(SongRequests.VideoID).DO_UPDATE(SET(
+				SongRequests.SongTitle.SET(SongRequests.EXCLUDED.SongTitle),
+				SongRequests.ArtistName.SET(SongRequests.EXCLUDED.ArtistName),
+				SongRequests.ImageURL.SET(SongRequests.EXCLUDED.ImageURL),
+			))
+		}
+		if _, err := stmt.ExecContext(ctx, tx); err != nil {
+			return result, fmt.Errorf("song %s: %w", v.VideoID, err)
+		}
+		countImported(&result.Songs, exists)
+	}
+
+	// bundle request id to the id it has in this database
+	requestIDs := map[int32]int32{}
+	for _, v := range b.Requests {
+		existing := []model.SongRequestRequesters{}
+		err := SELECT(SongRequestRequesters.ID).FROM(SongRequestRequesters).WHERE(
+			SongRequestRequesters.VideoID.EQ(String(v.VideoID)).
+				AND(SongRequestRequesters.TwitchUsername.EQ(String(v.TwitchUsername))).
+				AND(SongRequestRequesters.RequestedAt.EQ(String(v.RequestedAt))),
+		).LIMIT(1).QueryContext(ctx, tx, &existing)
+		if err != nil {
+			return result, err
+		}
+		if len(existing) > 0 && existing[0].ID != nil {
+			requestIDs[v.ID] = *existing[0].ID
+			result.Requests.Skipped++
+			continue
+		}
+		res, err := SongRequestRequesters.INSERT(SongRequestRequesters.MutableColumns).MODEL(model.SongRequestRequesters{
+			VideoID:        v.VideoID,
+			TwitchUserID:   v.TwitchUserID,
+			TwitchUsername: v.TwitchUsername,
+			RequestedAt:    v.RequestedAt,
+			Source:         v.Source,
+			Outcome:        v.Outcome,
+			OutcomeReason:  v.OutcomeReason,
+			OutcomeAt:      v.OutcomeAt,
+		}).ExecContext(ctx, tx)
+		if err != nil {
+			return result, fmt.Errorf("request %d for %s: %w", v.ID, v.VideoID, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return result, err
+		}
+		requestIDs[v.ID] = int32(id)
+		result.Requests.Added++
+	}
+
+	for _, v := range b.Plays {
+		exists, err := rowExists(ctx, tx, Plays, Plays.VideoID.EQ(String(v.VideoID)).AND(Plays.StartedAt.EQ(String(v.StartedAt))))
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Plays.Skipped++
+			continue
+		}
+		play := model.Plays{
+			VideoID:         v.VideoID,
+			SongTitle:       v.SongTitle,
+			ArtistName:      v.ArtistName,
+			ImageURL:        v.ImageURL,
+			DurationSeconds: v.DurationSeconds,
+			StartedAt:       v.StartedAt,
+			EndedAt:         v.EndedAt,
+			PlayedSeconds:   v.PlayedSeconds,
+			Skipped:         v.Skipped,
+			SkippedBy:       v.SkippedBy,
+		}
+		if id, ok := requestIDs[v.RequestID]; ok && v.RequestID != 0 {
+			play.RequestID = &id
+		}
+		if _, err := Plays.INSERT(Plays.MutableColumns).MODEL(play).ExecContext(ctx, tx); err != nil {
+			return result, fmt.Errorf("play of %s at %s: %w", v.VideoID, v.StartedAt, err)
+		}
+		result.Plays.Added++
+	}
+
+	for _, v := range b.Credits {
+		exists, err := rowExists(ctx, tx, RequestCredits, RequestCredits.TwitchUserID.EQ(String(v.TwitchUserID)))
+		if err != nil {
+			return result, err
+		}
+		stmt := RequestCredits.INSERT(RequestCredits.AllColumns).MODEL(model.RequestCredits(v))
+		if exists {
+			if mode != ImportOverwrite {
+				result.Credits.Skipped++
+				continue
+			}
+			stmt = stmt.ON_CONFLICT(RequestCredits.TwitchUserID).DO_UPDATE(SET(
+				RequestCredits.TwitchUsername.SET(RequestCredits.EXCLUDED.TwitchUsername),
+				RequestCredits.Credits.SET(RequestCredits.EXCLUDED.Credits),
+			))
+		}
+		if _, err := stmt.ExecContext(ctx, tx); err != nil {
+			return result, fmt.Errorf("credits of %s: %w", v.TwitchUsername, err)
+		}
+		countImported(&result.Credits, exists)
+	}
+
+	for _, v := range b.Blocklist {
+		exists, err := rowExists(ctx, tx, Blocklist, Blocklist.Kind.EQ(String(v.Kind)).AND(Blocklist.Value.EQ(String(v.Value))))
+		if err != nil {
+			return result, err
+		}
+		stmt := Blocklist.INSERT(Blocklist.AllColumns).MODEL(model.Blocklist(v))
+		if exists {
+			if mode != ImportOverwrite {
+				result.Blocklist.Skipped++
+				continue
+			}
+			stmt = stmt.ON_CONFLICT(Blocklist.Kind, Blocklist.Value).DO_UPDATE(SET(
+				Blocklist.Label.SET(Blocklist.EXCLUDED.Label),
+				Blocklist.CreatedAt.SET(Blocklist.EXCLUDED.CreatedAt),
+			))
+		}
+		if _, err := stmt.ExecContext(ctx, tx); err != nil {
+			return result, fmt.Errorf("blocklist %s %s: %w", v.Kind, v.Value, err)
+		}
+		countImported(&result.Blocklist, exists)
+	}
+
+	return result, tx.Commit()
+}
+
+// WriteHistoryCSV writes every request, newest first, with the song it was for
+func (s *Store) WriteHistoryCSV(ctx context.Context, w io.Writer) error {
+	rows, err := s.Requests.History(ctx, HistoryFilter{})
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	err = cw.Write([]string{"id", "requested_at", "video_id", "song_title", "artist_name", "twitch_user_id", "twitch_username", "source", "outcome", "outcome_reason"})
+	if err != nil {
+		return err
+	}
+	for _, v := range rows {
+		err = cw.Write([]string{
+			strconv.Itoa(int(v.ID)), v.RequestedAt, v.VideoID, csvText(v.SongTitle), csvText(v.ArtistName),
+			v.TwitchUserID, csvText(v.TwitchUsername), v.Source, v.Outcome, csvText(v.OutcomeReason),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText keeps spreadsheets from running titles and reasons chat controls as formulas
+func csvText(v string) string {
+	if v != "" && strings.ContainsAny(v[:1], "=+-@\t\r") {
+		return "'" + v
+	}
+	return v
+}
+
+func rowExists(ctx context.Context, db qrm.DB, table Table, condition BoolExpression) (bool, error) {
+	var result struct {
+		Count int64 `alias:"count"`
+	}
+	err := SELECT(COUNT(STAR).AS("count")).FROM(table).WHERE(condition).QueryContext(ctx, db, &result)
+	return result.Count > 0, err
+}
+
+func countImported(c *ImportCounts, existed bool) {
+	if existed {
+		c.Updated++
+	} else {
+		c.Added++
+	}
+}
+
+func derefInt32(v *int32) int32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
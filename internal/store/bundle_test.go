@@ -0,0 +1,214 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+)
+
+func testBundle() Bundle {
+	return Bundle{
+		Version: BundleVersion,
+		Settings: []BundleSetting{
+			{Key: "permission_sr", Value: "subscriber"},
+			{Key: "token_key_check", Value: "never imported"},
+		},
+		Songs: []BundleSong{
+			{VideoID: "aaaaaaaaaaa", SongTitle: "Song", ArtistName: "Band"},
+		},
+		Requests: []BundleRequest{
+			{ID: 7, VideoID: "aaaaaaaaaaa", TwitchUsername: "alice", RequestedAt: "2026-05-01T10:00:00Z", Outcome: "played"},
+		},
+		Plays: []BundlePlay{
+			{VideoID: "aaaaaaaaaaa", StartedAt: "2026-05-01T10:05:00Z", RequestID: 7},
+		},
+		Credits: []BundleCredit{
+			{TwitchUserID: "100", TwitchUsername: "alice", Credits: 3},
+		},
+		Blocklist: []BundleBlocklistItem{
+			{Kind: "user", Value: "200", Label: "troll", CreatedAt: "2026-05-01T09:00:00Z"},
+		},
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		mode         ImportMode
+		wantSetting  string
+		wantCredits  int
+		wantSettings ImportCounts
+	}{
+		{"skip keeps existing values", ImportSkip, "everyone", 1, ImportCounts{Skipped: 1}},
+		{"overwrite replaces them", ImportOverwrite, "subscriber", 3, ImportCounts{Updated: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t)
+			if err := s.Settings.Set(ctx, "permission_sr", "everyone"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Users.GrantCredits(ctx, "100", "alice", 1); err != nil {
+				t.Fatal(err)
+			}
+			// an unrelated request takes id 1 so the bundle ids have to be remapped
+			addTestRequest(t, s, "zzzzzzzzzzz", "Other", "Band", "zed", time.Now())
+
+			result, err := s.Import(ctx, testBundle(), tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Settings != tt.wantSettings {
+				t.Errorf("settings counts = %+v, want %+v", result.Settings, tt.wantSettings)
+			}
+			if got, _ := s.Settings.Get(ctx, "permission_sr"); got != tt.wantSetting {
+				t.Errorf("permission_sr = %q, want %q", got, tt.wantSetting)
+			}
+			if _, err := s.Settings.Get(ctx, "token_key_check"); err != ErrNotFound {
+				t.Errorf("token key settings must not be imported, Get() = %v", err)
+			}
+			if got, _ := s.Users.Credits(ctx, "100"); got != tt.wantCredits {
+				t.Errorf("credits = %d, want %d", got, tt.wantCredits)
+			}
+			if banned, _ := s.Users.IsBanned(ctx, "200"); !banned {
+				t.Error("ban was not imported")
+			}
+
+			// importing again must not duplicate history
+			again, err := s.Import(ctx, testBundle(), tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Requests.Added != 0 || again.Plays.Added != 0 {
+				t.Errorf("second import added history %+v %+v", again.Requests, again.Plays)
+			}
+			rows, err := s.Requests.History(ctx, HistoryFilter{Requester: "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("history has %d requests by alice, want 1", len(rows))
+			}
+			plays, err := s.Plays.List(ctx, time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var imported *model.Plays
+			for i := range plays {
+				if plays[i].VideoID == "aaaaaaaaaaa" {
+					imported = &plays[i]
+				}
+			}
+			if imported == nil || imported.RequestID == nil || *imported.RequestID != rows[0].ID {
+				t.Errorf("imported play should point at request %d, got %+v", rows[0].ID, imported)
+			}
+		})
+	}
+}
+
+func TestImportVersion(t *testing.T) {
+	s := newTestStore(t)
+	for _, version := range []int{0, BundleVersion + 1} {
+		b := testBundle()
+		b.Version = version
+		if _, err := s.Import(context.Background(), b, ImportSkip); err == nil {
+			t.Errorf("Import() of bundle version %d should fail", version)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newTestStore(t)
+	if _, err := src.Import(ctx, testBundle(), ImportSkip); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Settings.Set(ctx, "twitch_access_token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		includeTokens bool
+		wantToken     bool
+	}{
+		{"tokens left out by default", false, false},
+		{"tokens on request", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := src.Export(ctx, ExportOptions{IncludeTokens: tt.includeTokens})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hasToken := false
+			for _, v := range b.Settings {
+				if v.Key == "twitch_access_token" {
+					hasToken = v.Value == "secret"
+				}
+			}
+			if hasToken != tt.wantToken {
+				t.Errorf("token exported = %v, want %v", hasToken, tt.wantToken)
+			}
+			dst := newTestStore(t)
+			result, err := dst.Import(ctx, b, ImportSkip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Requests.Added != 1 || result.Plays.Added != 1 || result.Songs.Added != 1 || result.Credits.Added != 1 || result.Blocklist.Added != 1 {
+				t.Errorf("round trip import = %+v", result)
+			}
+		})
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	addTestRequest(t, s, "aaaaaaaaaaa", `=HYPERLINK("http://evil.example","x")`, "-Band-", "alice", time.Now())
+
+	buf := bytes.Buffer{}
+	if err := s.WriteHistoryCSV(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("WriteHistoryCSV() wrote %d records, want a header and 1 row", len(records))
+	}
+	row := records[1]
+	if row[3] != `'=HYPERLINK("http://evil.example","x")` || row[4] != "'-Band-" {
+		t.Errorf("WriteHistoryCSV() left formulas in %q and %q", row[3], row[4])
+	}
+	if row[2] != "aaaaaaaaaaa" || row[6] != "alice" {
+		t.Errorf("WriteHistoryCSV() changed plain values %v", row)
+	}
+}
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Song", "Song"},
+		{"", ""},
+		{"=1+1", "'=1+1"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+// PlaysSummary counts the plays in a time range
+type PlaysSummary struct {
+	Plays     int64 `alias:"plays_summary.plays"`
+	Skipped   int64 `alias:"plays_summary.skipped"`
+	Requested int64 `alias:"plays_summary.requested"`
+}
+
+// SkipRate is zero when nothing played
+func (s PlaysSummary) SkipRate() float64 {
+	if s.Plays == 0 {
+		return 0
+	}
+	return float64(s.Skipped) / float64(s.Plays)
+}
+
+// PlaysRepo is everything Pear Desktop played, requested or not
+type PlaysRepo interface {
+	// Start saves a play as it begins and returns its id for Finish
+	Start(ctx context.Context, play model.Plays) (int32, error)
+	Finish(ctx context.Context, id int32, playedSeconds int, skipped bool, skippedBy string) error
+	// List is oldest first, zero times leave that side open
+	List(ctx context.Context, from time.Time, to time.Time) ([]model.Plays, error)
+	// Summary counts over the same range as List
+	Summary(ctx context.Context, from time.Time, to time.Time) (PlaysSummary, error)
+}
+
+type sqlitePlays struct {
+	db *sql.DB
+}
+
+func (r *sqlitePlays) Start(ctx context.Context, play model.Plays) (int32, error) {
+	stmt := Plays.INSERT(Plays.MutableColumns).MODEL(play)
+	res, err := stmt.ExecContext(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int32(id), err
+}
+
+func (r *sqlitePlays) Finish(ctx context.Context, id int32, playedSeconds int, skipped bool, skippedBy string) error {
+	stmt := Plays.UPDATE(Plays.EndedAt, Plays.PlayedSeconds, Plays.Skipped, Plays.SkippedBy).
+		SET(String(FormatTime(time.Now())), Int(int64(playedSeconds)), Bool(skipped), String(skippedBy)).
+		WHERE(Plays.ID.EQ(Int32(id)))
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func playsBetween(from time.Time, to time.Time) BoolExpression {
+	condition := Bool(true)
+	if !from.IsZero() {
+		condition = condition.AND(Plays.StartedAt.GT_EQ(String(FormatTime(from))))
+	}
+	if !to.IsZero() {
+		condition = condition.AND(Plays.StartedAt.LT(String(FormatTime(to))))
+	}
+	return condition
+}
+
+func (r *sqlitePlays) List(ctx context.Context, from time.Time, to time.Time) ([]model.Plays, error) {
+	results := []model.Plays{}
+	stmt := SELECT(Plays.AllColumns).FROM(Plays).WHERE(playsBetween(from, to)).ORDER_BY(Plays.StartedAt.ASC(), Plays.ID.ASC())
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
+
+func (r *sqlitePlays) Summary(ctx context.Context, from time.Time, to time.Time) (PlaysSummary, error) {
+	result := PlaysSummary{}
+	stmt := SELECT(
+		COUNT(STAR).AS("plays_summary.plays"),
+		COALESCE(SUMi(CAST(Plays.Skipped).AS_INTEGER()), Int(0)).AS("plays_summary.skipped"),
+		COUNT(Plays.RequestID).AS("plays_summary.requested"),
+	).FROM(Plays).WHERE(playsBetween(from, to))
+	err := stmt.QueryContext(ctx, r.db, &result)
+	return result, err
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+)
+
+func TestPlays(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, videoID := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"} {
+		_, err := s.Plays.Start(ctx, model.Plays{
+			VideoID:         videoID,
+			SongTitle:       "Song " + videoID,
+			ArtistName:      "Band",
+			DurationSeconds: 200,
+			StartedAt:       FormatTime(day.Add(time.Duration(i) * time.Hour)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []string
+	}{
+		{"open range is oldest first", time.Time{}, time.Time{}, []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"}},
+		{"from is inclusive", day.Add(time.Hour), time.Time{}, []string{"bbbbbbbbbbb", "ccccccccccc"}},
+		{"to is exclusive", time.Time{}, day.Add(time.Hour), []string{"aaaaaaaaaaa"}},
+		{"empty range", day.Add(24 * time.Hour), time.Time{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plays, err := s.Plays.List(ctx, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plays) != len(tt.want) {
+				t.Fatalf("List() returned %d plays, want %d", len(plays), len(tt.want))
+			}
+			for i, v := range plays {
+				if v.VideoID != tt.want[i] {
+					t.Errorf("List()[%d] = %s, want %s", i, v.VideoID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlaysFinish(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	id, err := s.Plays.Start(ctx, model.Plays{
+		VideoID:   "aaaaaaaaaaa",
+		StartedAt: FormatTime(time.Now()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Plays.Finish(ctx, id, 42, true, "mod"); err != nil {
+		t.Fatal(err)
+	}
+	plays, err := s.Plays.List(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plays) != 1 {
+		t.Fatalf("List() returned %d plays, want 1", len(plays))
+	}
+	p := plays[0]
+	if p.PlayedSeconds != 42 || !p.Skipped || p.SkippedBy != "mod" || p.EndedAt == "" {
+		t.Errorf("Finish() saved %+v", p)
+	}
+}
+
+func TestPlaysSummary(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	summary, err := s.Plays.Summary(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (PlaysSummary{}) {
+		t.Errorf("Summary() without plays = %+v", summary)
+	}
+
+	addTestRequest(t, s, "aaaaaaaaaaa", "Song", "Band", "alice", day)
+	rows, err := s.Requests.History(ctx, HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID := rows[0].ID
+	for i, skipped := range []bool{true, false, true, true} {
+		play := model.Plays{
+			VideoID:   "aaaaaaaaaaa",
+			StartedAt: FormatTime(day.Add(time.Duration(i) * time.Hour)),
+		}
+		if i%2 == 0 {
+			play.RequestID = &requestID
+		}
+		id, err := s.Plays.Start(ctx, play)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Plays.Finish(ctx, id, 10, skipped, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want PlaysSummary
+	}{
+		{"open range", time.Time{}, time.Time{}, PlaysSummary{Plays: 4, Skipped: 3, Requested: 2}},
+		{"from", day.Add(time.Hour), time.Time{}, PlaysSummary{Plays: 3, Skipped: 2, Requested: 1}},
+		{"to", time.Time{}, day.Add(2 * time.Hour), PlaysSummary{Plays: 2, Skipped: 1, Requested: 1}},
+		{"empty range", day.Add(24 * time.Hour), time.Time{}, PlaysSummary{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := s.Plays.Summary(ctx, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary != tt.want {
+				t.Errorf("Summary() = %+v, want %+v", summary, tt.want)
+			}
+		})
+	}
+	if rate := (PlaysSummary{Plays: 4, Skipped: 3}).SkipRate(); rate != 0.75 {
+		t.Errorf("SkipRate() = %v, want 0.75", rate)
+	}
+}
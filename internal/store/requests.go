@@ -0,0 +1,220 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+type HistoryFilter struct {
+	// Requester is matched case insensitive, Artist and Query as substrings
+	Requester string
+	Artist    string
+	Query     string
+	Outcome   songrequests.RequestOutcome
+	// From is inclusive and To exclusive, zero leaves that side open
+	From time.Time
+	To   time.Time
+	// Limit zero returns every row
+	Limit  int
+	Offset int
+}
+
+type HistoryRow struct {
+	ID             int32  `alias:"song_request_requesters.id"`
+	VideoID        string `alias:"song_requests.video_id"`
+	SongTitle      string `alias:"song_requests.song_title"`
+	ArtistName     string `alias:"song_requests.artist_name"`
+	ImageURL       string `alias:"song_requests.image_url"`
+	TwitchUserID   string `alias:"song_request_requesters.twitch_user_id"`
+	TwitchUsername string `alias:"song_request_requesters.twitch_username"`
+	RequestedAt    string `alias:"song_request_requesters.requested_at"`
+	Source         string `alias:"song_request_requesters.source"`
+	Outcome        string `alias:"song_request_requesters.outcome"`
+	OutcomeReason  string `alias:"song_request_requesters.outcome_reason"`
+}
+
+// HistorySong is one song in the history with how often it was requested
+type HistorySong struct {
+	VideoID         string `alias:"history_song.video_id"`
+	SongTitle       string `alias:"history_song.song_title"`
+	ArtistName      string `alias:"history_song.artist_name"`
+	ImageURL        string `alias:"history_song.image_url"`
+	Count           int64  `alias:"history_song.count"`
+	LastRequestedAt string `alias:"history_song.last_requested_at"`
+}
+
+// RequestsRepo is the !sr history, one song row per videoId and one requester row per request
+type RequestsRepo interface {
+	Add(ctx context.Context, song model.SongRequests, requester model.SongRequestRequesters) error
+	// SetOutcome moves the newest request for videoID that is in one of the from outcomes
+	// and returns its id, 0 when there was none
+	SetOutcome(ctx context.Context, videoID string, from []songrequests.RequestOutcome, outcome songrequests.RequestOutcome, reason string) (int32, error)
+	// Song returns ErrNotFound when the videoId was never requested
+	Song(ctx context.Context, videoID string) (model.SongRequests, error)
+	// Request returns ErrNotFound when there is no request with that id
+	Request(ctx context.Context, id int32) (HistoryRow, error)
+	// History is newest first
+	History(ctx context.Context, filter HistoryFilter) ([]HistoryRow, error)
+	// CountHistory ignores Limit and Offset
+	CountHistory(ctx context.Context, filter HistoryFilter) (int64, error)
+	// HistorySongs counts requests per song over filter leaving out rejected ones, most requested first
+	HistorySongs(ctx context.Context, filter HistoryFilter, limit int) ([]HistorySong, error)
+}
+
+type sqliteRequests struct {
+	db *sql.DB
+}
+
+func (r *sqliteRequests) Add(ctx context.Context, song model.SongRequests, requester model.SongRequestRequesters) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := SongRequests.INSERT(SongRequests.AllColumns).MODEL(song).ON_CONFLICT(SongRequests.VideoID).DO_NOTHING()
+	_, err = stmt.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+	stmt = SongRequestRequesters.INSERT(SongRequestRequesters.MutableColumns).MODEL(requester)
+	_, err = stmt.ExecContext(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteRequests) SetOutcome(ctx context.Context, videoID string, from []songrequests.RequestOutcome, outcome songrequests.RequestOutcome, reason string) (int32, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	fromExpressions := []Expression{}
+	for _, v := range from {
+		fromExpressions = append(fromExpressions, String(v))
+	}
+	results := []model.SongRequestRequesters{}
+	stmt := SELECT(SongRequestRequesters.ID).FROM(SongRequestRequesters).WHERE(
+		SongRequestRequesters.VideoID.EQ(String(videoID)).AND(SongRequestRequesters.Outcome.IN(fromExpressions...)),
+	).ORDER_BY(SongRequestRequesters.ID.DESC()).LIMIT(1)
+	err = stmt.QueryContext(ctx, tx, &results)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 || results[0].ID == nil {
+		return 0, nil
+	}
+	id := *results[0].ID
+	update := SongRequestRequesters.UPDATE(SongRequestRequesters.Outcome, SongRequestRequesters.OutcomeReason, SongRequestRequesters.OutcomeAt).
+		SET(String(outcome), String(reason), String(FormatTime(time.Now()))).
+		WHERE(SongRequestRequesters.ID.EQ(Int32(id)))
+	_, err = update.ExecContext(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *sqliteRequests) Song(ctx context.Context, videoID string) (model.SongRequests, error) {
+	results := []model.SongRequests{}
+	stmt := SELECT(SongRequests.AllColumns).FROM(SongRequests).WHERE(SongRequests.VideoID.EQ(String(videoID)))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	if err != nil {
+		return model.SongRequests{}, err
+	}
+	if len(results) == 0 {
+		return model.SongRequests{}, ErrNotFound
+	}
+	return results[0], nil
+}
+
+func historyCondition(filter HistoryFilter) BoolExpression {
+	condition := Bool(true)
+	if v := strings.TrimSpace(filter.Requester); v != "" {
+		condition = condition.AND(LOWER(SongRequestRequesters.TwitchUsername).EQ(String(strings.ToLower(v))))
+	}
+	if v := strings.TrimSpace(filter.Artist); v != "" {
+		condition = condition.AND(SongRequests.ArtistName.LIKE(String("%" + v + "%")))
+	}
+	if v := strings.TrimSpace(filter.Query); v != "" {
+		condition = condition.AND(SongRequests.SongTitle.LIKE(String("%" + v + "%")).OR(SongRequests.ArtistName.LIKE(String("%" + v + "%"))))
+	}
+	if filter.Outcome != "" {
+		condition = condition.AND(SongRequestRequesters.Outcome.EQ(String(filter.Outcome)))
+	}
+	if !filter.From.IsZero() {
+		condition = condition.AND(SongRequestRequesters.RequestedAt.GT_EQ(String(FormatTime(filter.From))))
+	}
+	if !filter.To.IsZero() {
+		condition = condition.AND(SongRequestRequesters.RequestedAt.LT(String(FormatTime(filter.To))))
+	}
+	return condition
+}
+
+var historyColumns = ProjectionList{
+	SongRequestRequesters.ID, SongRequests.VideoID, SongRequests.SongTitle, SongRequests.ArtistName, SongRequests.ImageURL,
+	SongRequestRequesters.TwitchUserID, SongRequestRequesters.TwitchUsername, SongRequestRequesters.RequestedAt,
+	SongRequestRequesters.Source, SongRequestRequesters.Outcome, SongRequestRequesters.OutcomeReason,
+}
+
+var historyTables = SongRequestRequesters.INNER_JOIN(SongRequests, SongRequests.VideoID.EQ(SongRequestRequesters.VideoID))
+
+func (r *sqliteRequests) Request(ctx context.Context, id int32) (HistoryRow, error) {
+	rows := []HistoryRow{}
+	stmt := SELECT(historyColumns).FROM(historyTables).WHERE(SongRequestRequesters.ID.EQ(Int32(id)))
+	err := stmt.QueryContext(ctx, r.db, &rows)
+	if err != nil {
+		return HistoryRow{}, err
+	}
+	if len(rows) == 0 {
+		return HistoryRow{}, ErrNotFound
+	}
+	return rows[0], nil
+}
+
+func (r *sqliteRequests) History(ctx context.Context, filter HistoryFilter) ([]HistoryRow, error) {
+	rows := []HistoryRow{}
+	stmt := SELECT(historyColumns).FROM(historyTables).WHERE(historyCondition(filter)).
+		ORDER_BY(SongRequestRequesters.RequestedAt.DESC(), SongRequestRequesters.ID.DESC())
+	if filter.Limit > 0 {
+		stmt = stmt.LIMIT(int64(filter.Limit)).OFFSET(int64(max(filter.Offset, 0)))
+	}
+	err := stmt.QueryContext(ctx, r.db, &rows)
+	return rows, err
+}
+
+func (r *sqliteRequests) CountHistory(ctx context.Context, filter HistoryFilter) (int64, error) {
+	var result struct {
+		Count int64 `alias:"count"`
+	}
+	stmt := SELECT(COUNT(STAR).AS("count")).FROM(historyTables).WHERE(historyCondition(filter))
+	err := stmt.QueryContext(ctx, r.db, &result)
+	return result.Count, err
+}
+
+func (r *sqliteRequests) HistorySongs(ctx context.Context, filter HistoryFilter, limit int) ([]HistorySong, error) {
+	results := []HistorySong{}
+	condition := historyCondition(filter).AND(SongRequestRequesters.Outcome.NOT_EQ(String(songrequests.RequestOutcomeRejected)))
+	lastRequestedAt := MAX(SongRequestRequesters.RequestedAt)
+	stmt := SELECT(
+		SongRequests.VideoID.AS("history_song.video_id"), SongRequests.SongTitle.AS("history_song.song_title"),
+		SongRequests.ArtistName.AS("history_song.artist_name"), SongRequests.ImageURL.AS("history_song.image_url"),
+		COUNT(STAR).AS("history_song.count"), lastRequestedAt.AS("history_song.last_requested_at"),
+	).FROM(historyTables).WHERE(condition).
+		GROUP_BY(SongRequests.VideoID).
+		ORDER_BY(COUNT(STAR).DESC(), lastRequestedAt.DESC()).
+		LIMIT(int64(limit))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
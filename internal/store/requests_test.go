@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+)
+
+func addTestRequest(t *testing.T, s *Store, videoID string, title string, artist string, login string, at time.Time) {
+	t.Helper()
+	err := s.Requests.Add(context.Background(), model.SongRequests{
+		VideoID:    videoID,
+		SongTitle:  title,
+		ArtistName: artist,
+	}, model.SongRequestRequesters{
+		VideoID:        videoID,
+		TwitchUserID:   "id-" + login,
+		TwitchUsername: login,
+		RequestedAt:    FormatTime(at),
+		Source:         songrequests.RequestSourceChat,
+		Outcome:        songrequests.RequestOutcomeQueued,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestsHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	addTestRequest(t, s, "aaaaaaaaaaa", "First Song", "Some Band", "Alice", day.Add(time.Hour))
+	addTestRequest(t, s, "bbbbbbbbbbb", "Second Song", "Other Band", "bob", day.Add(2*time.Hour))
+	addTestRequest(t, s, "aaaaaaaaaaa", "First Song", "Some Band", "bob", day.Add(26*time.Hour))
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		want   []string
+	}{
+		{"everything newest first", HistoryFilter{}, []string{"bob", "bob", "Alice"}},
+		{"requester ignores case", HistoryFilter{Requester: "ALICE"}, []string{"Alice"}},
+		{"artist substring", HistoryFilter{Artist: "Other"}, []string{"bob"}},
+		{"query matches title", HistoryFilter{Query: "First"}, []string{"bob", "Alice"}},
+		{"from is inclusive", HistoryFilter{From: day.Add(2 * time.Hour)}, []string{"bob", "bob"}},
+		{"to is exclusive", HistoryFilter{To: day.Add(2 * time.Hour)}, []string{"Alice"}},
+		{"outcome", HistoryFilter{Outcome: songrequests.RequestOutcomePlayed}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := s.Requests.History(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, v := range rows {
+				got = append(got, v.TwitchUsername)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("History() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("History() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestsHistoryPages(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	addTestRequest(t, s, "aaaaaaaaaaa", "First Song", "Some Band", "alice", day.Add(time.Hour))
+	addTestRequest(t, s, "bbbbbbbbbbb", "Second Song", "Other Band", "bob", day.Add(2*time.Hour))
+	addTestRequest(t, s, "aaaaaaaaaaa", "First Song", "Some Band", "carol", day.Add(3*time.Hour))
+	addTestRequest(t, s, "ccccccccccc", "Third Song", "Some Band", "dave", day.Add(4*time.Hour))
+	_, err := s.Requests.SetOutcome(ctx, "ccccccccccc", []songrequests.RequestOutcome{songrequests.RequestOutcomeQueued}, songrequests.RequestOutcomeRejected, "blocked")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.Requests.History(ctx, HistoryFilter{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].TwitchUsername != "carol" || rows[1].TwitchUsername != "bob" {
+		t.Errorf("History() second and third row = %+v", rows)
+	}
+
+	total, err := s.Requests.CountHistory(ctx, HistoryFilter{Artist: "Some", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Errorf("CountHistory() = %d, want 3", total)
+	}
+
+	songs, err := s.Requests.HistorySongs(ctx, HistoryFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rejected request is left out, ties go to the song requested last
+	if len(songs) != 2 || songs[0].VideoID != "aaaaaaaaaaa" || songs[0].Count != 2 || songs[1].VideoID != "bbbbbbbbbbb" {
+		t.Fatalf("HistorySongs() = %+v", songs)
+	}
+	if songs[0].LastRequestedAt != FormatTime(day.Add(3*time.Hour)) {
+		t.Errorf("HistorySongs() last requested at %q", songs[0].LastRequestedAt)
+	}
+	songs, err = s.Requests.HistorySongs(ctx, HistoryFilter{Requester: "bob"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(songs) != 1 || songs[0].VideoID != "bbbbbbbbbbb" || songs[0].Count != 1 {
+		t.Errorf("HistorySongs() for bob = %+v", songs)
+	}
+
+	row, err := s.Requests.Request(ctx, rows[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.VideoID != "bbbbbbbbbbb" || row.TwitchUsername != "bob" {
+		t.Errorf("Request() = %+v", row)
+	}
+	if _, err := s.Requests.Request(ctx, 1000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Request() of an unknown id = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRequestsSetOutcome(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	now := time.Now()
+	addTestRequest(t, s, "aaaaaaaaaaa", "Song", "Band", "alice", now.Add(-time.Hour))
+	addTestRequest(t, s, "aaaaaaaaaaa", "Song", "Band", "bob", now)
+
+	queued := []songrequests.RequestOutcome{songrequests.RequestOutcomeQueued}
+	id, err := s.Requests.SetOutcome(ctx, "aaaaaaaaaaa", queued, songrequests.RequestOutcomePlayed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 {
+		t.Fatal("SetOutcome() found no queued request")
+	}
+	rows, err := s.Requests.History(ctx, HistoryFilter{Outcome: songrequests.RequestOutcomePlayed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].TwitchUsername != "bob" {
+		t.Errorf("SetOutcome() should move the newest request, played rows = %+v", rows)
+	}
+
+	id, err = s.Requests.SetOutcome(ctx, "ccccccccccc", queued, songrequests.RequestOutcomePlayed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 0 {
+		t.Errorf("SetOutcome() for an unknown videoId = %d, want 0", id)
+	}
+}
+
+func TestRequestsSong(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	addTestRequest(t, s, "aaaaaaaaaaa", "Song", "Band", "alice", time.Now())
+	// a second request keeps the first song row
+	addTestRequest(t, s, "aaaaaaaaaaa", "Renamed", "Band", "bob", time.Now())
+
+	song, err := s.Requests.Song(ctx, "aaaaaaaaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if song.SongTitle != "Song" {
+		t.Errorf("Song().SongTitle = %q, want %q", song.SongTitle, "Song")
+	}
+	if _, err := s.Requests.Song(ctx, "ccccccccccc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Song() of an unknown videoId = %v, want ErrNotFound", err)
+	}
+}
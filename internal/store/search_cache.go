@@ -0,0 +1,135 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+const (
+	searchCacheKindQuery = "query"
+	searchCacheKindVideo = "video"
+)
+
+// SearchCacheRepo remembers what a !sr query or videoId resolved to so repeat requests skip Pear's search
+type SearchCacheRepo interface {
+	// Get returns ErrNotFound when nothing is cached for the query, expiring entries is up to the caller
+	Get(ctx context.Context, query string) (*songrequests.SongResult, time.Time, error)
+	// Put caches song for the query and for its videoId
+	Put(ctx context.Context, query string, song *songrequests.SongResult) error
+	// Invalidate drops the entries of a query or videoId, for a videoId also every query that resolved to it
+	Invalidate(ctx context.Context, queryOrVideoID string) (int64, error)
+	Clear(ctx context.Context) (int64, error)
+	// Prune drops entries cached before the time
+	Prune(ctx context.Context, before time.Time) (int64, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+type sqliteSearchCache struct {
+	db *sql.DB
+}
+
+func (r *sqliteSearchCache) Get(ctx context.Context, query string) (*songrequests.SongResult, time.Time, error) {
+	condition := SearchCache.Kind.EQ(String(searchCacheKindQuery)).AND(SearchCache.Lookup.EQ(String(songrequests.NormalizeSearchQuery(query))))
+	if videoID := strings.TrimSpace(query); songrequests.IsVideoID(videoID) {
+		condition = condition.OR(SearchCache.Kind.EQ(String(searchCacheKindVideo)).AND(SearchCache.Lookup.EQ(String(videoID))))
+	}
+	results := []model.SearchCache{}
+	// a videoId entry wins over a query that happens to look the same
+	stmt := SELECT(SearchCache.AllColumns).FROM(SearchCache).WHERE(condition).ORDER_BY(SearchCache.Kind.DESC()).LIMIT(1)
+	err := stmt.QueryContext(ctx, r.db, &results)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(results) == 0 {
+		return nil, time.Time{}, ErrNotFound
+	}
+	v := results[0]
+	cachedAt, _ := time.Parse(time.RFC3339, v.CachedAt)
+	return &songrequests.SongResult{
+		Title:           v.SongTitle,
+		Artist:          v.ArtistName,
+		VideoID:         v.VideoID,
+		RawTimeData:     v.RawTimeData,
+		ImageUrl:        v.ImageURL,
+		SearchOrigin:    "SearchCache",
+		DurationSeconds: int(v.DurationSeconds),
+		VideoType:       v.VideoType,
+	}, cachedAt, nil
+}
+
+func (r *sqliteSearchCache) Put(ctx context.Context, query string, song *songrequests.SongResult) error {
+	entry := model.SearchCache{
+		VideoID:         song.VideoID,
+		SongTitle:       song.Title,
+		ArtistName:      song.Artist,
+		ImageURL:        song.ImageUrl,
+		RawTimeData:     song.RawTimeData,
+		DurationSeconds: int32(song.DurationSeconds),
+		VideoType:       song.VideoType,
+		CachedAt:        FormatTime(time.Now()),
+	}
+	videoEntry := entry
+	videoEntry.Kind = searchCacheKindVideo
+	videoEntry.Lookup = song.VideoID
+	entries := []model.SearchCache{videoEntry}
+	if strings.TrimSpace(query) != song.VideoID {
+		queryEntry := entry
+		queryEntry.Kind = searchCacheKindQuery
+		queryEntry.Lookup = songrequests.NormalizeSearchQuery(query)
+		entries = append(entries, queryEntry)
+	}
+
+	stmt := SearchCache.INSERT(SearchCache.AllColumns).MODELS(entries).
+		ON_CONFLICT(SearchCache.Kind, SearchCache.Lookup).DO_UPDATE(SET(
+		SearchCache.VideoID.SET(SearchCache.EXCLUDED.VideoID),
+		SearchCache.SongTitle.SET(SearchCache.EXCLUDED.SongTitle),
+		SearchCache.ArtistName.SET(SearchCache.EXCLUDED.ArtistName),
+		SearchCache.ImageURL.SET(SearchCache.EXCLUDED.ImageURL),
+		SearchCache.RawTimeData.SET(SearchCache.EXCLUDED.RawTimeData),
+		SearchCache.DurationSeconds.SET(SearchCache.EXCLUDED.DurationSeconds),
+		SearchCache.VideoType.SET(SearchCache.EXCLUDED.VideoType),
+		SearchCache.CachedAt.SET(SearchCache.EXCLUDED.CachedAt),
+	))
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteSearchCache) Invalidate(ctx context.Context, queryOrVideoID string) (int64, error) {
+	condition := SearchCache.Kind.EQ(String(searchCacheKindQuery)).AND(SearchCache.Lookup.EQ(String(songrequests.NormalizeSearchQuery(queryOrVideoID))))
+	if videoID := strings.TrimSpace(queryOrVideoID); songrequests.IsVideoID(videoID) {
+		condition = condition.OR(SearchCache.VideoID.EQ(String(videoID)))
+	}
+	return r.delete(ctx, condition)
+}
+
+func (r *sqliteSearchCache) Clear(ctx context.Context) (int64, error) {
+	return r.delete(ctx, Bool(true))
+}
+
+func (r *sqliteSearchCache) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return r.delete(ctx, SearchCache.CachedAt.LT(String(FormatTime(before))))
+}
+
+func (r *sqliteSearchCache) Count(ctx context.Context) (int64, error) {
+	var result struct {
+		Count int64 `alias:"count"`
+	}
+	err := SELECT(COUNT(STAR).AS("count")).FROM(SearchCache).QueryContext(ctx, r.db, &result)
+	return result.Count, err
+}
+
+func (r *sqliteSearchCache) delete(ctx context.Context, condition BoolExpression) (int64, error) {
+	res, err := SearchCache.DELETE().WHERE(condition).ExecContext(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,87 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+type SettingsRepo interface {
+	All(ctx context.Context) ([]model.Settings, error)
+	// Get returns ErrNotFound when the key was never saved
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string) error
+	// SetMany saves all settings in one transaction, a failure saves none of them
+	SetMany(ctx context.Context, settings map[string]string) error
+	Delete(ctx context.Context, key string) error
+	ListPrefix(ctx context.Context, prefix string) ([]model.Settings, error)
+}
+
+type sqliteSettings struct {
+	db *sql.DB
+}
+
+func (r *sqliteSettings) All(ctx context.Context) ([]model.Settings, error) {
+	results := []model.Settings{}
+	stmt := SELECT(Settings.Key, Settings.Value).FROM(Settings)
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
+
+func (r *sqliteSettings) Get(ctx context.Context, key string) (string, error) {
+	results := []model.Settings{}
+	stmt := SELECT(Settings.Value).FROM(Settings).WHERE(Settings.Key.EQ(String(key)))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", ErrNotFound
+	}
+	return results[0].Value, nil
+}
+
+func setSettingStmt(key string, value string) InsertStatement {
+	return Settings.INSERT(Settings.AllColumns).MODEL(model.Settings{
+		Key:   key,
+		Value: value,
+	}).ON_CONFLICT(Settings.Key).DO_UPDATE(SET(
+		Settings.Value.SET(Settings.EXCLUDED.Value),
+	))
+}
+
+func (r *sqliteSettings) Set(ctx context.Context, key string, value string) error {
+	_, err := setSettingStmt(key, value).ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteSettings) SetMany(ctx context.Context, settings map[string]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for k, v := range settings {
+		if _, err := setSettingStmt(k, v).ExecContext(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqliteSettings) Delete(ctx context.Context, key string) error {
+	stmt := Settings.DELETE().WHERE(Settings.Key.EQ(String(key)))
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteSettings) ListPrefix(ctx context.Context, prefix string) ([]model.Settings, error) {
+	results := []model.Settings{}
+	stmt := SELECT(Settings.Key, Settings.Value).FROM(Settings).WHERE(Settings.Key.LIKE(String(prefix + "%")))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
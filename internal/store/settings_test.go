@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	if _, err := s.Settings.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing key = %v, want ErrNotFound", err)
+	}
+
+	steps := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"insert", "permission_sr", "everyone"},
+		{"overwrite", "permission_sr", "subscriber,vip"},
+		{"second key", "permission_skip", "moderator"},
+		{"empty value", "twitch_song_request_reward_id", ""},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Settings.Set(ctx, tt.key, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Settings.Get(ctx, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.value {
+				t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.value)
+			}
+		})
+	}
+
+	all, err := s.Settings.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("All() returned %d settings, want 3", len(all))
+	}
+	prefixed, err := s.Settings.ListPrefix(ctx, "permission_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefixed) != 2 {
+		t.Errorf("ListPrefix() returned %d settings, want 2", len(prefixed))
+	}
+
+	if err := s.Settings.Delete(ctx, "permission_sr"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Settings.Get(ctx, "permission_sr"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSettingsSetMany(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	if err := s.Settings.Set(ctx, "permission_sr", "everyone"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"permission_sr":   "moderator",
+		"permission_skip": "vip",
+	}
+	if err := s.Settings.SetMany(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range want {
+		if got, _ := s.Settings.Get(ctx, k); got != v {
+			t.Errorf("Get(%q) = %q, want %q", k, got, v)
+		}
+	}
+
+	// a canceled request saves nothing
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Settings.SetMany(canceled, map[string]string{"permission_sr": "everyone"}); err == nil {
+		t.Fatal("SetMany() with a canceled context should fail")
+	}
+	if got, _ := s.Settings.Get(ctx, "permission_sr"); got != "moderator" {
+		t.Errorf("Get() after a failed SetMany() = %q, want moderator", got)
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+type SongCount struct {
+	VideoID    string `json:"video_id"`
+	SongTitle  string `json:"title"`
+	ArtistName string `json:"artist"`
+	Count      int64  `json:"count"`
+}
+
+type ArtistCount struct {
+	ArtistName string `json:"artist"`
+	Count      int64  `json:"count"`
+}
+
+type RequesterCount struct {
+	TwitchUsername string `json:"login"`
+	Count          int64  `json:"count"`
+}
+
+type UserStats struct {
+	Requests int64 `json:"requests"`
+	Played   int64 `json:"played"`
+	Skipped  int64 `json:"skipped"`
+	// TopSong is empty when the user never requested anything
+	TopSong SongCount `json:"top_song"`
+}
+
+// StatsRepo aggregates request history, rejected requests are never counted
+// and a zero since counts everything
+type StatsRepo interface {
+	TopSongs(ctx context.Context, since time.Time, limit int) ([]SongCount, error)
+	TopArtists(ctx context.Context, since time.Time, limit int) ([]ArtistCount, error)
+	TopRequesters(ctx context.Context, since time.Time, limit int) ([]RequesterCount, error)
+	// User matches userID, or the login for rows saved before user ids were kept
+	User(ctx context.Context, userID string, userLogin string, since time.Time) (UserStats, error)
+}
+
+type sqliteStats struct {
+	db *sql.DB
+}
+
+var countStar = COUNT(STAR)
+
+func countedRequests(since time.Time) BoolExpression {
+	condition := SongRequestRequesters.Outcome.NOT_EQ(String(songrequests.RequestOutcomeRejected))
+	if !since.IsZero() {
+		condition = condition.AND(SongRequestRequesters.RequestedAt.GT_EQ(String(FormatTime(since))))
+	}
+	return condition
+}
+
+var requestsWithSongs = SongRequestRequesters.INNER_JOIN(SongRequests, SongRequests.VideoID.EQ(SongRequestRequesters.VideoID))
+
+func (r *sqliteStats) topSongs(ctx context.Context, condition BoolExpression, limit int) ([]SongCount, error) {
+	results := []SongCount{}
+	stmt := SELECT(
+		SongRequests.VideoID.AS("song_count.video_id"), SongRequests.SongTitle.AS("song_count.song_title"),
+		SongRequests.ArtistName.AS("song_count.artist_name"), countStar.AS("song_count.count"),
+	).FROM(requestsWithSongs).WHERE(condition).
+		GROUP_BY(SongRequests.VideoID).
+		ORDER_BY(countStar.DESC(), MAX(SongRequestRequesters.RequestedAt).DESC()).
+		LIMIT(int64(limit))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
+
+func (r *sqliteStats) TopSongs(ctx context.Context, since time.Time, limit int) ([]SongCount, error) {
+	return r.topSongs(ctx, countedRequests(since), limit)
+}
+
+func (r *sqliteStats) TopArtists(ctx context.Context, since time.Time, limit int) ([]ArtistCount, error) {
+	results := []ArtistCount{}
+	stmt := SELECT(
+		SongRequests.ArtistName.AS("artist_count.artist_name"), countStar.AS("artist_count.count"),
+	).FROM(requestsWithSongs).WHERE(countedRequests(since).AND(SongRequests.ArtistName.NOT_EQ(String("")))).
+		GROUP_BY(SongRequests.ArtistName).
+		ORDER_BY(countStar.DESC(), MAX(SongRequestRequesters.RequestedAt).DESC()).
+		LIMIT(int64(limit))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
+
+func (r *sqliteStats) TopRequesters(ctx context.Context, since time.Time, limit int) ([]RequesterCount, error) {
+	results := []RequesterCount{}
+	// the user id survives renames, older rows only have the login
+	requester := Raw("COALESCE(NULLIF(song_request_requesters.twitch_user_id, ''), LOWER(song_request_requesters.twitch_username))")
+	stmt := SELECT(
+		MAX(SongRequestRequesters.TwitchUsername).AS("requester_count.twitch_username"), countStar.AS("requester_count.count"),
+	).FROM(SongRequestRequesters).WHERE(countedRequests(since)).
+		GROUP_BY(requester).
+		ORDER_BY(countStar.DESC(), MAX(SongRequestRequesters.RequestedAt).DESC()).
+		LIMIT(int64(limit))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	return results, err
+}
+
+func (r *sqliteStats) User(ctx context.Context, userID string, userLogin string, since time.Time) (UserStats, error) {
+	stats := UserStats{}
+	isUser := SongRequestRequesters.TwitchUserID.EQ(String(userID)).OR(
+		SongRequestRequesters.TwitchUserID.EQ(String("")).AND(LOWER(SongRequestRequesters.TwitchUsername).EQ(String(strings.ToLower(userLogin)))),
+	)
+	condition := countedRequests(since).AND(isUser)
+
+	outcomes := []struct {
+		Outcome string `alias:"outcome"`
+		Count   int64  `alias:"count"`
+	}{}
+	stmt := SELECT(SongRequestRequesters.Outcome.AS("outcome"), countStar.AS("count")).
+		FROM(SongRequestRequesters).WHERE(condition).GROUP_BY(SongRequestRequesters.Outcome)
+	err := stmt.QueryContext(ctx, r.db, &outcomes)
+	if err != nil {
+		return stats, err
+	}
+	for _, v := range outcomes {
+		stats.Requests += v.Count
+		switch v.Outcome {
+		case songrequests.RequestOutcomePlayed:
+			stats.Played += v.Count
+		case songrequests.RequestOutcomeSkipped:
+			// skipped songs did start playing
+			stats.Played += v.Count
+			stats.Skipped += v.Count
+		}
+	}
+
+	top, err := r.topSongs(ctx, condition, 1)
+	if err != nil {
+		return stats, err
+	}
+	if len(top) > 0 {
+		stats.TopSong = top[0]
+	}
+	return stats, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/tokencrypt"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// FormatTime is how timestamps are stored, UTC RFC3339 sorts the same as text and as time
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Store holds the one shared database handle and the repositories the app goes through
+type Store struct {
+	db        *sql.DB
+	Settings  SettingsRepo
+	Requests  RequestsRepo
+	Plays     PlaysRepo
+	Stats     StatsRepo
+	Users     UsersRepo
+	Search    SearchCacheRepo
+	Blocklist BlocklistRepo
+	// cipher is set by EnableTokenEncryption
+	cipher *tokencrypt.Cipher
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{
+		db:        db,
+		Settings:  &sqliteSettings{db: db},
+		Requests:  &sqliteRequests{db: db},
+		Plays:     &sqlitePlays{db: db},
+		Stats:     &sqliteStats{db: db},
+		Users:     &sqliteUsers{db: db},
+		Search:    &sqliteSearchCache{db: db},
+		Blocklist: &sqliteBlocklist{db: db},
+	}
+}
+
+// Open opens the database at path, migrations are expected to be applied already
+func Open(path string) (*Store, error) {
+	db, err := databaseconn.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+// OpenMemory returns a store on an empty migrated in-memory database
+func OpenMemory() (*Store, error) {
+	db, err := databaseconn.OpenMemory()
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
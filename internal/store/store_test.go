@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestFormatTime(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"utc", time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), "2026-03-01T12:30:00Z"},
+		{"offset is moved to utc", time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600)), "2026-03-01T11:30:00Z"},
+		{"sub seconds are dropped", time.Date(2026, 3, 1, 12, 30, 0, 999, time.UTC), "2026-03-01T12:30:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatTime(tt.in); got != tt.want {
+				t.Errorf("FormatTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/databaseconn"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/tokencrypt"
+)
+
+// tokenKeyCheck is sealed into the database so a wrong passphrase or a replaced key file shows up at start
+const tokenKeyCheck = "pear-desktop-twitch-song-requests"
+
+// ErrWrongTokenKey means the tokens in the database were sealed with another key or passphrase
+var ErrWrongTokenKey = errors.New("the Twitch tokens were encrypted with a different key file or passphrase")
+
+// ErrTokenPassphraseRequired means the database was set up with a passphrase that is not set now
+var ErrTokenPassphraseRequired = errors.New("the Twitch tokens are encrypted with a passphrase, set " + tokencrypt.PassphraseEnvVar)
+
+// sealedSettings encrypts the Twitch tokens on the way in and decrypts them on the way out,
+// everything else passes through
+type sealedSettings struct {
+	SettingsRepo
+	cipher *tokencrypt.Cipher
+}
+
+func (r *sealedSettings) All(ctx context.Context) ([]model.Settings, error) {
+	results, err := r.SettingsRepo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(results), nil
+}
+
+func (r *sealedSettings) ListPrefix(ctx context.Context, prefix string) ([]model.Settings, error) {
+	results, err := r.SettingsRepo.ListPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(results), nil
+}
+
+func (r *sealedSettings) Get(ctx context.Context, key string) (string, error) {
+	v, err := r.SettingsRepo.Get(ctx, key)
+	if err != nil || !data.IsEncryptedSetting(key) {
+		return v, err
+	}
+	return r.cipher.Open(v)
+}
+
+func (r *sealedSettings) Set(ctx context.Context, key string, value string) error {
+	if data.IsEncryptedSetting(key) {
+		value = r.cipher.Seal(value)
+	}
+	return r.SettingsRepo.Set(ctx, key, value)
+}
+
+func (r *sealedSettings) SetMany(ctx context.Context, settings map[string]string) error {
+	sealed := make(map[string]string, len(settings))
+	for k, v := range settings {
+		if data.IsEncryptedSetting(k) {
+			v = r.cipher.Seal(v)
+		}
+		sealed[k] = v
+	}
+	return r.SettingsRepo.SetMany(ctx, sealed)
+}
+
+// openAll leaves out tokens that cannot be decrypted, to the app that is the same as being logged out
+func (r *sealedSettings) openAll(results []model.Settings) []model.Settings {
+	opened := []model.Settings{}
+	for _, v := range results {
+		if data.IsEncryptedSetting(v.Key) {
+			value, err := r.cipher.Open(v.Value)
+			if err != nil {
+				continue
+			}
+			v.Value = value
+		}
+		opened = append(opened, v)
+	}
+	return opened
+}
+
+// EnableTokenEncryption keeps the Twitch tokens encrypted from now on and encrypts plaintext tokens saved by older versions.
+// The key comes from the passphrase in tokencrypt.PassphraseEnvVar when set, otherwise from the key file in dataDir.
+// ErrWrongTokenKey and ErrTokenPassphraseRequired leave the store as it was.
+func (s *Store) EnableTokenEncryption(ctx context.Context, dataDir string) error {
+	raw := &sqliteSettings{db: s.db}
+	var c *tokencrypt.Cipher
+	if passphrase := os.Getenv(tokencrypt.PassphraseEnvVar); passphrase != "" {
+		salt, err := raw.Get(ctx, data.DB_KEY_TOKEN_KEY_SALT)
+		if err == ErrNotFound {
+			salt = hex.EncodeToString(tokencrypt.NewSalt())
+			err = raw.Set(ctx, data.DB_KEY_TOKEN_KEY_SALT, salt)
+		}
+		if err != nil {
+			return err
+		}
+		saltBytes, err := hex.DecodeString(salt)
+		if err != nil {
+			return errors.New("token key salt is corrupt")
+		}
+		c, err = tokencrypt.FromPassphrase(passphrase, saltBytes)
+		if err != nil {
+			return err
+		}
+	} else {
+		// a salt means the tokens were sealed with a passphrase, a new key file would never open them
+		_, err := raw.Get(ctx, data.DB_KEY_TOKEN_KEY_SALT)
+		if err == nil {
+			return ErrTokenPassphraseRequired
+		}
+		if err != ErrNotFound {
+			return err
+		}
+		c, _, err = tokencrypt.LoadKeyFile(dataDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	check, err := raw.Get(ctx, data.DB_KEY_TOKEN_KEY_CHECK)
+	if err == nil {
+		if v, err := c.Open(check); err != nil || v != tokenKeyCheck {
+			return ErrWrongTokenKey
+		}
+	} else if err == ErrNotFound {
+		err = raw.Set(ctx, data.DB_KEY_TOKEN_KEY_CHECK, c.Seal(tokenKeyCheck))
+		if err != nil {
+			return err
+		}
+	} else {
+		return err
+	}
+
+	sealed := 0
+	for _, key := range []string{data.DB_KEY_TWITCH_ACCESS_TOKEN, data.DB_KEY_TWITCH_ACCESS_TOKEN_BOT} {
+		v, err := raw.Get(ctx, key)
+		if err == ErrNotFound || tokencrypt.IsSealed(v) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = raw.Set(ctx, key, c.Seal(v))
+		if err != nil {
+			return err
+		}
+		sealed++
+	}
+	if sealed > 0 {
+		// the plaintext stays in free pages until the file is rebuilt
+		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return err
+		}
+	}
+	s.cipher = c
+	s.Settings = &sealedSettings{SettingsRepo: raw, cipher: c}
+	return nil
+}
+
+// ForgetTokenKey deletes the tokens, the key check and the passphrase salt so the next EnableTokenEncryption
+// starts over with whatever key is set up then, the Twitch accounts have to be logged in again
+func (s *Store) ForgetTokenKey(ctx context.Context) error {
+	raw := &sqliteSettings{db: s.db}
+	for _, key := range []string{data.DB_KEY_TWITCH_ACCESS_TOKEN, data.DB_KEY_TWITCH_ACCESS_TOKEN_BOT, data.DB_KEY_TOKEN_KEY_CHECK, data.DB_KEY_TOKEN_KEY_SALT} {
+		if err := raw.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealSetting is how an imported value is saved, tokens get sealed when encryption is on
+func (s *Store) sealSetting(key string, value string) string {
+	if s.cipher != nil && data.IsEncryptedSetting(key) && !tokencrypt.IsSealed(value) {
+		return s.cipher.Seal(value)
+	}
+	return value
+}
+
+// WriteRedactedCopy writes a copy of the database without tokens, the session secret and the token key settings,
+// safe to attach to a bug report
+func (s *Store) WriteRedactedCopy(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.New(path + " already exists")
+	}
+	_, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path)
+	if err != nil {
+		return err
+	}
+	db, err := databaseconn.Open(path)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	settings, err := (&sqliteSettings{db: db}).All(ctx)
+	if err == nil {
+		keys := []string{}
+		for _, v := range settings {
+			if data.IsSecretSetting(v.Key) || data.IsTokenKeySetting(v.Key) {
+				keys = append(keys, v.Key)
+			}
+		}
+		for _, key := range keys {
+			if err = (&sqliteSettings{db: db}).Delete(ctx, key); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		// deleted rows linger in free pages until the file is rebuilt
+		_, err = db.ExecContext(ctx, "VACUUM")
+	}
+	if err == nil {
+		_, err = db.ExecContext(ctx, "PRAGMA journal_mode = DELETE")
+	}
+	db.Close()
+	if err != nil {
+		os.Remove(path)
+		os.Remove(path + "-wal")
+		os.Remove(path + "-shm")
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/data"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/tokencrypt"
+)
+
+func TestEnableTokenEncryption(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// first start, then the start that is checked
+		firstPassphrase string
+		passphrase      string
+		newKeyFile      bool
+		wantErr         error
+	}{
+		{"same key file", "", "", false, nil},
+		{"lost key file", "", "", true, ErrWrongTokenKey},
+		{"same passphrase", "secret", "secret", false, nil},
+		{"wrong passphrase", "secret", "other", false, ErrWrongTokenKey},
+		{"passphrase not set", "secret", "", false, ErrTokenPassphraseRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := newTestStore(t)
+			if err := s.Settings.Set(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN, "plain-token"); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(tokencrypt.PassphraseEnvVar, tt.firstPassphrase)
+			if err := s.EnableTokenEncryption(ctx, dir); err != nil {
+				t.Fatal(err)
+			}
+			raw := &sqliteSettings{db: s.db}
+			if v, _ := raw.Get(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN); !tokencrypt.IsSealed(v) {
+				t.Fatalf("token was left in plaintext: %q", v)
+			}
+
+			if tt.newKeyFile {
+				os.Remove(filepath.Join(dir, tokencrypt.KeyFileName))
+			}
+			restarted := New(s.db)
+			t.Setenv(tokencrypt.PassphraseEnvVar, tt.passphrase)
+			err := restarted.EnableTokenEncryption(ctx, dir)
+			if err != tt.wantErr {
+				t.Fatalf("EnableTokenEncryption() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				// a failed start must not touch the tokens
+				if v, _ := raw.Get(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN); !tokencrypt.IsSealed(v) {
+					t.Errorf("token changed after a failed start: %q", v)
+				}
+				if tt.wantErr == ErrTokenPassphraseRequired {
+					if _, err := os.Stat(filepath.Join(dir, tokencrypt.KeyFileName)); !os.IsNotExist(err) {
+						t.Error("a key file was created for a passphrase database")
+					}
+				}
+				return
+			}
+			if v, _ := restarted.Settings.Get(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN); v != "plain-token" {
+				t.Errorf("Get() = %q, want the decrypted token", v)
+			}
+			err = restarted.Settings.SetMany(ctx, map[string]string{data.DB_KEY_TWITCH_ACCESS_TOKEN: "new-token"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := raw.Get(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN); !tokencrypt.IsSealed(v) {
+				t.Errorf("SetMany() saved the token in plaintext: %q", v)
+			}
+		})
+	}
+}
+
+func TestForgetTokenKey(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := newTestStore(t)
+	t.Setenv(tokencrypt.PassphraseEnvVar, "secret")
+	if err := s.Settings.Set(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN, "plain-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnableTokenEncryption(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ForgetTokenKey(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// after a reset the database can move to a key file
+	t.Setenv(tokencrypt.PassphraseEnvVar, "")
+	if err := New(s.db).EnableTokenEncryption(ctx, dir); err != nil {
+		t.Fatalf("EnableTokenEncryption() after ForgetTokenKey() = %v", err)
+	}
+	if _, err := s.Settings.Get(ctx, data.DB_KEY_TWITCH_ACCESS_TOKEN); err != ErrNotFound {
+		t.Errorf("token still saved after ForgetTokenKey(), Get() = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+//lint:file-ignore ST1001 Dot imports by jet
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/gen/model"
+	. "github.com/azuridayo/pear-desktop-twitch-song-requests/gen/table"
+	"github.com/azuridayo/pear-desktop-twitch-song-requests/internal/songrequests"
+	. "github.com/go-jet/jet/v2/sqlite"
+)
+
+// UsersRepo covers what is kept per Twitch user, request credits and request bans
+type UsersRepo interface {
+	GrantCredits(ctx context.Context, userID string, userLogin string, credits int) error
+	Credits(ctx context.Context, userID string) (int, error)
+	// ConsumeCredit reports false when there was no credit left to take
+	ConsumeCredit(ctx context.Context, userID string) (bool, error)
+	RefundCredit(ctx context.Context, userID string) error
+	Ban(ctx context.Context, userID string, userLogin string) error
+	IsBanned(ctx context.Context, userID string) (bool, error)
+}
+
+type sqliteUsers struct {
+	db *sql.DB
+}
+
+func (r *sqliteUsers) GrantCredits(ctx context.Context, userID string, userLogin string, credits int) error {
+	stmt := RequestCredits.INSERT(RequestCredits.AllColumns).MODEL(model.RequestCredits{
+		TwitchUserID:   userID,
+		TwitchUsername: userLogin,
+		Credits:        int32(credits),
+	}).ON_CONFLICT(RequestCredits.TwitchUserID).DO_UPDATE(SET(
+		RequestCredits.Credits.SET(RequestCredits.Credits.ADD(RequestCredits.EXCLUDED.Credits)),
+		RequestCredits.TwitchUsername.SET(RequestCredits.EXCLUDED.TwitchUsername),
+	))
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteUsers) Credits(ctx context.Context, userID string) (int, error) {
+	results := []model.RequestCredits{}
+	stmt := SELECT(RequestCredits.Credits).FROM(RequestCredits).WHERE(RequestCredits.TwitchUserID.EQ(String(userID)))
+	err := stmt.QueryContext(ctx, r.db, &results)
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return int(results[0].Credits), nil
+}
+
+func (r *sqliteUsers) ConsumeCredit(ctx context.Context, userID string) (bool, error) {
+	stmt := RequestCredits.UPDATE(RequestCredits.Credits).
+		SET(RequestCredits.Credits.SUB(Int(1))).
+		WHERE(RequestCredits.TwitchUserID.EQ(String(userID)).AND(RequestCredits.Credits.GT(Int(0))))
+	res, err := stmt.ExecContext(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *sqliteUsers) RefundCredit(ctx context.Context, userID string) error {
+	stmt := RequestCredits.UPDATE(RequestCredits.Credits).
+		SET(RequestCredits.Credits.ADD(Int(1))).
+		WHERE(RequestCredits.TwitchUserID.EQ(String(userID)))
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteUsers) Ban(ctx context.Context, userID string, userLogin string) error {
+	stmt := Blocklist.INSERT(Blocklist.AllColumns).MODEL(model.Blocklist{
+		Kind:      songrequests.BlocklistKindUser,
+		Value:     userID,
+		Label:     userLogin,
+		CreatedAt: FormatTime(time.Now()),
+	}).ON_CONFLICT(Blocklist.Kind, Blocklist.Value).DO_NOTHING()
+	_, err := stmt.ExecContext(ctx, r.db)
+	return err
+}
+
+func (r *sqliteUsers) IsBanned(ctx context.Context, userID string) (bool, error) {
+	results := []model.Blocklist{}
+	stmt := SELECT(Blocklist.Value).FROM(Blocklist).WHERE(
+		Blocklist.Kind.EQ(String(songrequests.BlocklistKindUser)).AND(Blocklist.Value.EQ(String(userID))),
+	)
+	err := stmt.QueryContext(ctx, r.db, &results)
+	if err != nil {
+		return false, err
+	}
+	return len(results) > 0, nil
+}
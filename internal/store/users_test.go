@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUsersCredits(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	steps := []struct {
+		name string
+		run  func() error
+		want int
+	}{
+		{"unknown user has none", func() error { return nil }, 0},
+		{"grant", func() error { return s.Users.GrantCredits(ctx, "100", "viewer", 2) }, 2},
+		{"grants add up", func() error { return s.Users.GrantCredits(ctx, "100", "viewer", 3) }, 5},
+		{"consume", func() error {
+			ok, err := s.Users.ConsumeCredit(ctx, "100")
+			if err == nil && !ok {
+				t.Error("ConsumeCredit() = false with credits left")
+			}
+			return err
+		}, 4},
+		{"refund", func() error { return s.Users.RefundCredit(ctx, "100") }, 5},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Users.Credits(ctx, "100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Credits() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	ok, err := s.Users.ConsumeCredit(ctx, "200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("ConsumeCredit() = true for a user without credits")
+	}
+}
+
+func TestUsersBan(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	if err := s.Users.Ban(ctx, "100", "troll"); err != nil {
+		t.Fatal(err)
+	}
+	// banning twice is not an error
+	if err := s.Users.Ban(ctx, "100", "troll"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		userID string
+		want   bool
+	}{
+		{"100", true},
+		{"200", false},
+	}
+	for _, tt := range tests {
+		got, err := s.Users.IsBanned(ctx, tt.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsBanned(%q) = %v, want %v", tt.userID, got, tt.want)
+		}
+	}
+}
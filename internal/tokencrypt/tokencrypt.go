@@ -0,0 +1,117 @@
+package tokencrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// KeyFileName is created in the data directory, keep it out of bug reports
+	KeyFileName = "token.key"
+	// PassphraseEnvVar makes the key come from a passphrase instead of the key file
+	PassphraseEnvVar = "PEAR_SR_TOKEN_PASSPHRASE"
+
+	keySize       = 32
+	saltSize      = 16
+	kdfIterations = 600000
+	sealedPrefix  = "enc:v1:"
+)
+
+var ErrWrongKey = errors.New("value was encrypted with another key")
+
+// Cipher seals values with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, errors.New("token key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// LoadKeyFile reads the key file in dataDir, a missing file is created with a new random key
+func LoadKeyFile(dataDir string) (c *Cipher, created bool, err error) {
+	path := filepath.Join(dataDir, KeyFileName)
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key := make([]byte, keySize)
+		rand.Read(key)
+		err = os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600)
+		if err != nil {
+			return nil, false, err
+		}
+		c, err = New(key)
+		return c, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, false, errors.New(path + " is not a token key file")
+	}
+	c, err = New(key)
+	return c, false, err
+}
+
+// NewSalt is stored next to the data it protects, it is not a secret
+func NewSalt() []byte {
+	salt := make([]byte, saltSize)
+	rand.Read(salt)
+	return salt
+}
+
+func FromPassphrase(passphrase string, salt []byte) (*Cipher, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+// IsSealed tells sealed values apart from plaintext saved by older versions
+func IsSealed(v string) bool {
+	return strings.HasPrefix(v, sealedPrefix)
+}
+
+func (c *Cipher) Seal(plaintext string) string {
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// Open returns plaintext values unchanged, ErrWrongKey means the value is sealed but not with this key
+func (c *Cipher) Open(v string) (string, error) {
+	if !IsSealed(v) {
+		return v, nil
+	}
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(v, sealedPrefix))
+	if err != nil || len(b) < c.aead.NonceSize() {
+		return "", ErrWrongKey
+	}
+	plaintext, err := c.aead.Open(nil, b[:c.aead.NonceSize()], b[c.aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plaintext), nil
+}
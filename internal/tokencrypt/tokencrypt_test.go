@@ -0,0 +1,125 @@
+package tokencrypt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{1}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(bytes.Repeat([]byte{2}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := c.Seal("oauth-token")
+	if sealed == c.Seal("oauth-token") {
+		t.Error("Seal() should use a new nonce every time")
+	}
+
+	tests := []struct {
+		name    string
+		cipher  *Cipher
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"round trip", c, sealed, "oauth-token", nil},
+		{"plaintext passes through", c, "plain", "plain", nil},
+		{"other key", other, sealed, "", ErrWrongKey},
+		{"tampered", c, sealed[:len(sealed)-2] + "AA", "", ErrWrongKey},
+		{"not base64", c, sealedPrefix + "!!!", "", ErrWrongKey},
+		{"too short", c, sealedPrefix + "AAAA", "", ErrWrongKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Open(tt.in)
+			if err != tt.wantErr {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Open() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeySize(t *testing.T) {
+	for _, n := range []int{0, 16, 31, 33} {
+		if _, err := New(make([]byte, n)); err == nil {
+			t.Errorf("New() with a %d byte key should fail", n)
+		}
+	}
+}
+
+func TestFromPassphrase(t *testing.T) {
+	salt := NewSalt()
+	a, err := FromPassphrase("correct horse", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := a.Seal("token")
+
+	tests := []struct {
+		name       string
+		passphrase string
+		salt       []byte
+		wantErr    error
+	}{
+		{"same passphrase and salt", "correct horse", salt, nil},
+		{"other passphrase", "battery staple", salt, ErrWrongKey},
+		{"other salt", "correct horse", NewSalt(), ErrWrongKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := FromPassphrase(tt.passphrase, tt.salt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Open(sealed); err != tt.wantErr {
+				t.Errorf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	first, created, err := LoadKeyFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("LoadKeyFile() should create a missing key file")
+	}
+	info, err := os.Stat(filepath.Join(dir, KeyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 && runtime.GOOS != "windows" {
+		t.Errorf("key file mode = %v, want it private", perm)
+	}
+
+	second, created, err := LoadKeyFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("LoadKeyFile() should reuse the existing key file")
+	}
+	if v, err := second.Open(first.Seal("token")); err != nil || v != "token" {
+		t.Errorf("the reloaded key should open what the first sealed, got %q %v", v, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, KeyFileName), []byte("not hex\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadKeyFile(dir); err == nil {
+		t.Error("LoadKeyFile() should reject a corrupt key file")
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		// run against a cache of 3 that already holds a, b and c, set in that order
+		run  func(c *LRUCache[string, int])
+		want []string
+		gone []string
+	}{
+		{"oldest goes first", func(c *LRUCache[string, int]) { c.Set("d", 4) }, []string{"b", "c", "d"}, []string{"a"}},
+		{"get refreshes", func(c *LRUCache[string, int]) {
+			c.Get("a")
+			c.Set("d", 4)
+		}, []string{"a", "c", "d"}, []string{"b"}},
+		{"set of a known key refreshes", func(c *LRUCache[string, int]) {
+			c.Set("a", 10)
+			c.Set("d", 4)
+		}, []string{"a", "c", "d"}, []string{"b"}},
+		{"several evictions", func(c *LRUCache[string, int]) {
+			c.Set("d", 4)
+			c.Set("e", 5)
+		}, []string{"c", "d", "e"}, []string{"a", "b"}},
+		{"miss does not refresh", func(c *LRUCache[string, int]) {
+			c.Get("x")
+			c.Set("d", 4)
+		}, []string{"b", "c", "d"}, []string{"a", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRUCache[string, int](3, time.Hour)
+			c.Set("a", 1)
+			c.Set("b", 2)
+			c.Set("c", 3)
+			tt.run(c)
+			if c.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(tt.want))
+			}
+			for _, k := range tt.want {
+				if _, ok := c.Get(k); !ok {
+					t.Errorf("Get(%q) missed", k)
+				}
+			}
+			for _, k := range tt.gone {
+				if _, ok := c.Get(k); ok {
+					t.Errorf("Get(%q) should have been evicted", k)
+				}
+			}
+		})
+	}
+}
+
+func TestLRUCacheSetUpdatesValue(t *testing.T) {
+	c := NewLRUCache[string, int](2, time.Hour)
+	c.Set("a", 1)
+	c.Set("a", 2)
+	if v, _ := c.Get("a"); v != 2 {
+		t.Errorf("Get() = %d, want 2", v)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	c := NewLRUCache[string, int](10, 20*time.Millisecond)
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get() missed before the ttl")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() should miss after the ttl")
+	}
+	if c.Len() != 0 {
+		t.Errorf("an expired entry is dropped on Get(), Len() = %d", c.Len())
+	}
+
+	// a new ttl only applies to entries set afterwards
+	c.Set("old", 1)
+	c.SetTTL(time.Hour)
+	c.Set("new", 2)
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := c.Get("old"); ok {
+		t.Error("Get(old) should miss, it keeps the short ttl")
+	}
+	if _, ok := c.Get("new"); !ok {
+		t.Error("Get(new) missed, it was set with the long ttl")
+	}
+}
+
+func TestLRUCacheDeleteAndPurge(t *testing.T) {
+	c := NewLRUCache[string, int](10, time.Hour)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	c.Delete("b")
+	// deleting a missing key is a no-op
+	c.Delete("x")
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get() after Delete() should miss")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() after Delete() = %d, want 2", c.Len())
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() after Purge() = %d, want 0", c.Len())
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() after Purge() should miss")
+	}
+	// the cache keeps working after a purge
+	c.Set("d", 4)
+	if v, ok := c.Get("d"); !ok || v != 4 {
+		t.Errorf("Get() after Purge() and Set() = %d, %v", v, ok)
+	}
+}